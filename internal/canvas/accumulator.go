@@ -0,0 +1,241 @@
+package canvas
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Floating point buffer that accumulates weighted color samples per pixel. The
+// current estimate can be turned into a canvas at any time.
+type Accumulator struct {
+	width   int
+	height  int
+	sums    []FloatColor
+	weights []float64
+	// weighted sums of the samples with positive weights only, for pixels
+	// whose negative weights cancel out most of the positive ones
+	positiveSums    []FloatColor
+	positiveWeights []float64
+	// guard the sums of each row, as samples are spread across pixels
+	rows []sync.Mutex
+	// number of samples and running luminance statistics for noise estimation
+	counts         []int
+	luminanceMeans []float64
+	luminanceM2s   []float64
+}
+
+// Create a new accumulator with specified width and height.
+func NewAccumulator(width, height int) *Accumulator {
+	if width <= 0 || height <= 0 {
+		panic("accumulator width and height must be greater than 0")
+	}
+
+	size := width * height
+
+	return &Accumulator{
+		width:           width,
+		height:          height,
+		sums:            make([]FloatColor, size),
+		weights:         make([]float64, size),
+		positiveSums:    make([]FloatColor, size),
+		positiveWeights: make([]float64, size),
+		rows:            make([]sync.Mutex, height),
+		counts:          make([]int, size),
+		luminanceMeans:  make([]float64, size),
+		luminanceM2s:    make([]float64, size),
+	}
+}
+
+// Get accumulator width in pixels.
+func (a *Accumulator) Width() int {
+	return a.width
+}
+
+// Get accumulator height in pixels.
+func (a *Accumulator) Height() int {
+	return a.height
+}
+
+// Add a sample with the given weight to the pixel at coordinates (x, y).
+// Different pixels may be updated concurrently.
+func (a *Accumulator) AddSample(x, y int, color FloatColor, weight float64) {
+	a.splat(x, y, color, weight)
+	a.count(x, y, color)
+}
+
+// Add a sample taken at the offset (dx, dy) from the center of the pixel at
+// coordinates (x, y) to all pixels within the radius of the filter, weighted
+// by the filter at its offset from their centers. It only counts as a sample
+// of the pixel at (x, y) for the noise estimation. Different pixels may be
+// updated concurrently.
+func (a *Accumulator) AddFilteredSample(x, y int, dx, dy float64, color FloatColor, filter PixelFilter) {
+	radius := filter.Radius()
+	minX := int(math.Max(0, math.Ceil(float64(x)+dx-radius)))
+	maxX := int(math.Min(float64(a.width-1), math.Floor(float64(x)+dx+radius)))
+	minY := int(math.Max(0, math.Ceil(float64(y)+dy-radius)))
+	maxY := int(math.Min(float64(a.height-1), math.Floor(float64(y)+dy+radius)))
+
+	for py := minY; py <= maxY; py++ {
+		for px := minX; px <= maxX; px++ {
+			weight := filter.Weight(float64(x-px)+dx, float64(y-py)+dy)
+			if weight != 0 {
+				a.splat(px, py, color, weight)
+			}
+		}
+	}
+
+	a.count(x, y, color)
+}
+
+func (a *Accumulator) splat(x, y int, color FloatColor, weight float64) {
+	i := a.index(x, y)
+
+	a.rows[y].Lock()
+	defer a.rows[y].Unlock()
+
+	a.sums[i] = a.sums[i].Add(color.Scale(weight))
+	a.weights[i] += weight
+
+	if weight > 0 {
+		a.positiveSums[i] = a.positiveSums[i].Add(color.Scale(weight))
+		a.positiveWeights[i] += weight
+	}
+}
+
+func (a *Accumulator) count(x, y int, color FloatColor) {
+	i := a.index(x, y)
+
+	// Welford's online algorithm
+	a.counts[i]++
+	luminance := color.Luminance()
+	delta := luminance - a.luminanceMeans[i]
+	a.luminanceMeans[i] += delta / float64(a.counts[i])
+	a.luminanceM2s[i] += delta * (luminance - a.luminanceMeans[i])
+}
+
+// Remove all samples.
+func (a *Accumulator) Reset() {
+	for i := range a.sums {
+		a.sums[i] = FloatColor{}
+		a.weights[i] = 0
+		a.positiveSums[i] = FloatColor{}
+		a.positiveWeights[i] = 0
+		a.counts[i] = 0
+		a.luminanceMeans[i] = 0
+		a.luminanceM2s[i] = 0
+	}
+}
+
+// Add all samples of another accumulator of the same size, e.g. of a
+// completed pass. It must not be called concurrently with other updates.
+func (a *Accumulator) Merge(other *Accumulator) {
+	if a.width != other.width || a.height != other.height {
+		panic(fmt.Sprintf("can not merge a %dx%d accumulator into a %dx%d accumulator", other.width, other.height, a.width, a.height))
+	}
+
+	for i := range a.sums {
+		a.sums[i] = a.sums[i].Add(other.sums[i])
+		a.weights[i] += other.weights[i]
+		a.positiveSums[i] = a.positiveSums[i].Add(other.positiveSums[i])
+		a.positiveWeights[i] += other.positiveWeights[i]
+
+		if other.counts[i] == 0 {
+			continue
+		}
+
+		// parallel variant of Welford's algorithm by Chan et al.
+		n, m := float64(a.counts[i]), float64(other.counts[i])
+		delta := other.luminanceMeans[i] - a.luminanceMeans[i]
+
+		a.counts[i] += other.counts[i]
+		a.luminanceMeans[i] += delta * m / (n + m)
+		a.luminanceM2s[i] += other.luminanceM2s[i] + delta*delta*n*m/(n+m)
+	}
+}
+
+// Get the number of samples of the pixel at coordinates (x, y).
+func (a *Accumulator) Samples(x, y int) int {
+	return a.counts[a.index(x, y)]
+}
+
+// Get the current estimate of the pixel at coordinates (x, y). Where the
+// negative weights of a filter cancel out most of the positive ones, dividing
+// by their sum would blow up the estimate, so these pixels ignore the samples
+// with negative weights instead.
+func (a *Accumulator) Color(x, y int) FloatColor {
+	i := a.index(x, y)
+
+	if a.weights[i] > a.positiveWeights[i]/2 {
+		return a.sums[i].Scale(1 / a.weights[i])
+	}
+
+	if a.positiveWeights[i] == 0 {
+		return FloatColor{}
+	}
+
+	return a.positiveSums[i].Scale(1 / a.positiveWeights[i])
+}
+
+// Get the relative standard error of the luminance estimate of the pixel at
+// coordinates (x, y). Pixels with less than two samples have infinite error.
+func (a *Accumulator) Noise(x, y int) float64 {
+	i := a.index(x, y)
+
+	if a.counts[i] < 2 {
+		return math.Inf(1)
+	}
+
+	n := float64(a.counts[i])
+	variance := a.luminanceM2s[i] / (n - 1)
+	standardError := math.Sqrt(variance / n)
+
+	// avoid dividing by (almost) zero in dark regions
+	return standardError / math.Max(a.luminanceMeans[i], 1.0/math.MaxUint8)
+}
+
+// Get the standard deviation of the sample luminances of the pixel at
+// coordinates (x, y). Pixels with less than two samples have no deviation.
+func (a *Accumulator) Deviation(x, y int) float64 {
+	i := a.index(x, y)
+
+	if a.counts[i] < 2 {
+		return 0
+	}
+
+	return math.Sqrt(a.luminanceM2s[i] / float64(a.counts[i]-1))
+}
+
+// Get the mean relative standard error over all pixels.
+func (a *Accumulator) MeanNoise() float64 {
+	sum := 0.0
+
+	for y := 0; y < a.height; y++ {
+		for x := 0; x < a.width; x++ {
+			sum += a.Noise(x, y)
+		}
+	}
+
+	return sum / float64(a.width*a.height)
+}
+
+// Create a canvas from the current estimate.
+func (a *Accumulator) Canvas() *Canvas {
+	canvas := NewCanvas(a.width, a.height)
+
+	for y := 0; y < a.height; y++ {
+		for x := 0; x < a.width; x++ {
+			canvas.SetColor(x, y, a.Color(x, y).Color())
+		}
+	}
+
+	return canvas
+}
+
+func (a *Accumulator) index(x, y int) int {
+	if x < 0 || y < 0 || x >= a.width || y >= a.height {
+		panic(fmt.Sprintf("pixel coordinates out of bounds - tried to access pixel (%d, %d) in a %dx%d accumulator", x, y, a.width, a.height))
+	}
+
+	return y*a.width + x
+}
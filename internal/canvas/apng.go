@@ -0,0 +1,169 @@
+package canvas
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image/png"
+	"io"
+	"time"
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// Chunk of a PNG file.
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+// Write the canvases as frames of an animated PNG (APNG), looping forever. All
+// canvases must have the same size. If a file exists at the given path, it is
+// moved to "<path>.bak".
+// Source: https://wiki.mozilla.org/APNG_Specification
+func WriteApng(path string, frames []*Canvas, delay time.Duration) error {
+	if len(frames) == 0 {
+		return errors.New("animation has no frames")
+	}
+
+	var header []byte
+	data := make([][]byte, len(frames))
+
+	// the standard encoder compresses the frames, whose image data is then
+	// moved into the chunks of the animation
+	for i, frame := range frames {
+		if frame.width != frames[0].width || frame.height != frames[0].height {
+			return fmt.Errorf("frame %d has a different size than the first frame", i)
+		}
+
+		var buffer bytes.Buffer
+		if err := png.Encode(&buffer, frame.Image()); err != nil {
+			return fmt.Errorf("failed to encode frame %d: %w", i, err)
+		}
+
+		chunks, err := readPngChunks(buffer.Bytes())
+		if err != nil {
+			return fmt.Errorf("failed to read encoded frame %d: %w", i, err)
+		}
+
+		for _, chunk := range chunks {
+			switch chunk.kind {
+			case "IHDR":
+				header = chunk.data
+			case "IDAT":
+				data[i] = append(data[i], chunk.data...)
+			}
+		}
+	}
+
+	file, err := createOutputFile(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+
+	if _, err = writer.Write(pngSignature); err != nil {
+		return err
+	}
+
+	chunks := []pngChunk{
+		{"IHDR", header},
+		{"acTL", be32(uint32(len(frames)), 0)},
+	}
+
+	// frame control and frame data chunks share one sequence
+	sequence := uint32(0)
+
+	for i := range frames {
+		control := be32(sequence, uint32(frames[0].width), uint32(frames[0].height), 0, 0)
+		// delay in milliseconds, no disposal, no blending
+		control = binary.BigEndian.AppendUint16(control, uint16(delay.Milliseconds()))
+		control = binary.BigEndian.AppendUint16(control, 1000)
+		control = append(control, 0, 0)
+		chunks = append(chunks, pngChunk{"fcTL", control})
+		sequence++
+
+		// the first frame is the default image shown by decoders without APNG
+		// support
+		if i == 0 {
+			chunks = append(chunks, pngChunk{"IDAT", data[i]})
+		} else {
+			chunks = append(chunks, pngChunk{"fdAT", append(be32(sequence), data[i]...)})
+			sequence++
+		}
+	}
+
+	chunks = append(chunks, pngChunk{"IEND", nil})
+
+	for _, chunk := range chunks {
+		if err = writePngChunk(writer, chunk); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+// Split an encoded PNG file into its chunks.
+func readPngChunks(file []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(file, pngSignature) {
+		return nil, errors.New("missing PNG signature")
+	}
+
+	var chunks []pngChunk
+	rest := file[len(pngSignature):]
+
+	for len(rest) > 0 {
+		if len(rest) < 12 {
+			return nil, errors.New("truncated chunk")
+		}
+
+		length := binary.BigEndian.Uint32(rest)
+		if uint64(len(rest)) < 12+uint64(length) {
+			return nil, errors.New("truncated chunk")
+		}
+
+		chunks = append(chunks, pngChunk{
+			kind: string(rest[4:8]),
+			data: rest[8 : 8+length],
+		})
+		rest = rest[12+length:]
+	}
+
+	return chunks, nil
+}
+
+// Write a chunk with its length and checksum.
+func writePngChunk(writer io.Writer, chunk pngChunk) error {
+	body := append([]byte(chunk.kind), chunk.data...)
+
+	_, err := writer.Write(be32(uint32(len(chunk.data))))
+	if err != nil {
+		return err
+	}
+
+	if _, err = writer.Write(body); err != nil {
+		return err
+	}
+
+	_, err = writer.Write(be32(crc32.ChecksumIEEE(body)))
+
+	return err
+}
+
+// Encode numbers as consecutive big-endian 32-bit integers.
+func be32(values ...uint32) []byte {
+	encoded := make([]byte, 0, 4*len(values))
+
+	for _, value := range values {
+		encoded = binary.BigEndian.AppendUint32(encoded, value)
+	}
+
+	return encoded
+}
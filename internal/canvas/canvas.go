@@ -3,6 +3,8 @@ package canvas
 import (
 	"bufio"
 	"fmt"
+	"image"
+	_ "image/jpeg"
 	"math"
 	"os"
 	"strings"
@@ -67,39 +69,17 @@ func (canvas *Canvas) SetColor(x, y int, color Color) error {
 	return canvas.SetRGB(x, y, color.R, color.G, color.B)
 }
 
-func (canvas *Canvas) CreateSSAACanvas() *Canvas {
-	newCanvas := NewCanvas(canvas.width/2, canvas.height/2)
-
-	for i := 0; i < newCanvas.width; i++ {
-		for j := 0; j < newCanvas.height; j++ {
-			newCanvas.R[i][j] = averageBytes(canvas.R[i*2][j*2], canvas.R[i*2+1][j*2], canvas.R[i*2][j*2+1], canvas.R[i*2+1][j*2+1])
-			newCanvas.G[i][j] = averageBytes(canvas.G[i*2][j*2], canvas.G[i*2+1][j*2], canvas.G[i*2][j*2+1], canvas.G[i*2+1][j*2+1])
-			newCanvas.B[i][j] = averageBytes(canvas.B[i*2][j*2], canvas.B[i*2+1][j*2], canvas.B[i*2][j*2+1], canvas.B[i*2+1][j*2+1])
-		}
-	}
-
-	return newCanvas
-}
-
-func averageBytes(a, b, c, d uint8) uint8 {
-	return uint8((uint32(a) + uint32(b) + uint32(c) + uint32(d)) / 4)
-}
-
 // Write the canvas to a PPM (P6) file. If a file exists at the given path, it
 // is moved to "<path>.bak". Return an error if writing the file fails.
 func (canvas *Canvas) WriteToPpm(path string) error {
-	err := os.Rename(path, path+".bak")
-
-	if err != nil && !os.IsNotExist(err) {
-		return err
-	}
-
-	file, err := os.Create(path)
+	file, err := createOutputFile(path)
 
 	if err != nil {
 		return err
 	}
 
+	defer file.Close()
+
 	writer := bufio.NewWriter(file)
 
 	_, err = writer.WriteString(fmt.Sprintf("P6\n%d %d\n%d\n", canvas.width, canvas.height, math.MaxUint8))
@@ -133,6 +113,62 @@ func (canvas *Canvas) WriteToPpm(path string) error {
 	return nil
 }
 
+// Create a file at the given path. If a file exists at the path, it is moved
+// to "<path>.bak" first.
+func createOutputFile(path string) (*os.File, error) {
+	err := os.Rename(path, path+".bak")
+
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return os.Create(path)
+}
+
+// Convert the canvas to an image of the standard library.
+func (canvas *Canvas) Image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, canvas.width, canvas.height))
+
+	for j := 0; j < canvas.height; j++ {
+		for i := 0; i < canvas.width; i++ {
+			offset := img.PixOffset(i, j)
+			img.Pix[offset] = canvas.R[i][j]
+			img.Pix[offset+1] = canvas.G[i][j]
+			img.Pix[offset+2] = canvas.B[i][j]
+			img.Pix[offset+3] = math.MaxUint8
+		}
+	}
+
+	return img
+}
+
+// Read a PNG, JPEG or GIF image into a new canvas.
+func ReadImage(path string) (*Canvas, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	img, _, err := image.Decode(bufio.NewReader(file))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	canvas := NewCanvas(bounds.Dx(), bounds.Dy())
+
+	for i := 0; i < canvas.width; i++ {
+		for j := 0; j < canvas.height; j++ {
+			r, g, b, _ := img.At(bounds.Min.X+i, bounds.Min.Y+j).RGBA()
+			canvas.SetRGB(i, j, uint8(r>>8), uint8(g>>8), uint8(b>>8))
+		}
+	}
+
+	return canvas, nil
+}
+
 // Create string representation of the canvas.
 func (canvas Canvas) String() string {
 	var strBuilder strings.Builder
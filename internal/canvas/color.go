@@ -45,6 +45,16 @@ func (c Color) Merge(other Color, factor float64) Color {
 	}
 }
 
+// Multiply the color channel-wise with a filter color, e.g. light passing
+// through tinted glass.
+func (c Color) Filter(filter Color) Color {
+	return Color{
+		R: uint8(uint32(c.R) * uint32(filter.R) / math.MaxUint8),
+		G: uint8(uint32(c.G) * uint32(filter.G) / math.MaxUint8),
+		B: uint8(uint32(c.B) * uint32(filter.B) / math.MaxUint8),
+	}
+}
+
 // Get string representation of color.
 func (c Color) String() string {
 	return fmt.Sprintf("(%3d, %3d, %3d)", c.R, c.G, c.B)
@@ -0,0 +1,103 @@
+package canvas
+
+import "math"
+
+// Reconstruction filter that weights the samples of a pixel by their offset
+// from the pixel center. All filters are separable, i.e. the weight of an
+// offset (dx, dy) is the product of the one-dimensional weights of dx and dy.
+type PixelFilter interface {
+	// Get the offset from the pixel center along each axis beyond which
+	// samples have no weight.
+	Radius() float64
+	// Get the weight of a sample at the given offset from the pixel center.
+	// Weights may be negative.
+	Weight(dx, dy float64) float64
+}
+
+type separableFilter struct {
+	radius float64
+	weight func(d float64) float64
+}
+
+func (f separableFilter) Radius() float64 {
+	return f.radius
+}
+
+func (f separableFilter) Weight(dx, dy float64) float64 {
+	if math.Abs(dx) > f.radius || math.Abs(dy) > f.radius {
+		return 0
+	}
+
+	return f.weight(dx) * f.weight(dy)
+}
+
+// Create a box filter, which weights all samples within the radius equally. A
+// radius of 0.5 covers exactly one pixel.
+func NewBoxFilter(radius float64) PixelFilter {
+	return separableFilter{
+		radius: radius,
+		weight: func(float64) float64 {
+			return 1
+		},
+	}
+}
+
+// Create a tent filter, whose weights fall off linearly from the center.
+func NewTentFilter(radius float64) PixelFilter {
+	return separableFilter{
+		radius: radius,
+		weight: func(d float64) float64 {
+			return radius - math.Abs(d)
+		},
+	}
+}
+
+// Create a Gaussian filter with falloff alpha. The curve is shifted down so it
+// reaches zero at the radius.
+func NewGaussianFilter(radius, alpha float64) PixelFilter {
+	edge := math.Exp(-alpha * radius * radius)
+
+	return separableFilter{
+		radius: radius,
+		weight: func(d float64) float64 {
+			return math.Max(0, math.Exp(-alpha*d*d)-edge)
+		},
+	}
+}
+
+// Create a Mitchell-Netravali filter with the parameters b and c. Both 1/3 is
+// the usual tradeoff between blurring and ringing.
+// Source: https://pbr-book.org/3ed-2018/Sampling_and_Reconstruction/Image_Reconstruction#MitchellFilter
+func NewMitchellFilter(radius, b, c float64) PixelFilter {
+	return separableFilter{
+		radius: radius,
+		weight: func(d float64) float64 {
+			x := math.Abs(2 * d / radius)
+
+			if x > 1 {
+				return ((-b-6*c)*x*x*x + (6*b+30*c)*x*x + (-12*b-48*c)*x + (8*b + 24*c)) / 6
+			}
+
+			return ((12-9*b-6*c)*x*x*x + (-18+12*b+6*c)*x*x + (6 - 2*b)) / 6
+		},
+	}
+}
+
+// Create a Lanczos filter, i.e. a sinc function windowed by a sinc function
+// stretched to the radius.
+func NewLanczosFilter(radius float64) PixelFilter {
+	return separableFilter{
+		radius: radius,
+		weight: func(d float64) float64 {
+			return sinc(d) * sinc(d/radius)
+		},
+	}
+}
+
+func sinc(x float64) float64 {
+	if math.Abs(x) < 1e-5 {
+		return 1
+	}
+
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
@@ -0,0 +1,57 @@
+package canvas
+
+import "math"
+
+// RGB color with floating point channels, where 1 corresponds to 255 in Color.
+// Channels may exceed 1, e.g. for high dynamic range images.
+type FloatColor struct {
+	R float64
+	G float64
+	B float64
+}
+
+// Convert the color to floating point channels.
+func (c Color) Float() FloatColor {
+	return FloatColor{
+		R: float64(c.R) / math.MaxUint8,
+		G: float64(c.G) / math.MaxUint8,
+		B: float64(c.B) / math.MaxUint8,
+	}
+}
+
+// Convert the color to 8 bit channels. Channels are clamped to [0, 1] first.
+func (c FloatColor) Color() Color {
+	return Color{
+		R: floatToByte(c.R),
+		G: floatToByte(c.G),
+		B: floatToByte(c.B),
+	}
+}
+
+func (a FloatColor) Add(b FloatColor) FloatColor {
+	return FloatColor{a.R + b.R, a.G + b.G, a.B + b.B}
+}
+
+func (c FloatColor) Scale(f float64) FloatColor {
+	return FloatColor{c.R * f, c.G * f, c.B * f}
+}
+
+// Multiply the color channel-wise with another color.
+func (a FloatColor) Mul(b FloatColor) FloatColor {
+	return FloatColor{a.R * b.R, a.G * b.G, a.B * b.B}
+}
+
+// Get the relative luminance of the color (Rec. 709 coefficients).
+func (c FloatColor) Luminance() float64 {
+	return 0.2126*c.R + 0.7152*c.G + 0.0722*c.B
+}
+
+func floatToByte(f float64) uint8 {
+	if f <= 0 || math.IsNaN(f) {
+		return 0
+	} else if f >= 1 {
+		return math.MaxUint8
+	}
+
+	return uint8(f*math.MaxUint8 + 0.5)
+}
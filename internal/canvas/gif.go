@@ -0,0 +1,203 @@
+package canvas
+
+import (
+	"bufio"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"sort"
+	"time"
+)
+
+// Maximum number of colors of a GIF palette.
+const gifPaletteSize = 256
+
+// Write the canvases as frames of an animated GIF, looping forever. All
+// frames share one palette, which is quantized from their colors with the
+// median cut algorithm, and are dithered with Floyd-Steinberg error diffusion.
+// If a file exists at the given path, it is moved to "<path>.bak".
+func WriteGif(path string, frames []*Canvas, delay time.Duration) error {
+	if len(frames) == 0 {
+		return errors.New("animation has no frames")
+	}
+
+	palette := medianCutPalette(frames, gifPaletteSize)
+	animation := &gif.GIF{}
+
+	for _, frame := range frames {
+		img := frame.Image()
+		paletted := image.NewPaletted(img.Bounds(), palette)
+		draw.FloydSteinberg.Draw(paletted, img.Bounds(), img, image.Point{})
+
+		animation.Image = append(animation.Image, paletted)
+		// GIF delays are given in hundredths of a second
+		animation.Delay = append(animation.Delay, int(delay/(10*time.Millisecond)))
+	}
+
+	file, err := createOutputFile(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+
+	if err = gif.EncodeAll(writer, animation); err != nil {
+		return err
+	}
+
+	return writer.Flush()
+}
+
+// Bits per channel of the color histogram for palette quantization.
+const histogramBits = 5
+
+// Colors of the canvases that fall into one cell of the color histogram.
+type colorBin struct {
+	// mean color of the pixels in the bin
+	color [3]uint8
+	count int
+	sum   [3]int
+}
+
+// Find a palette of at most size colors for the canvases. The colors are
+// counted in a histogram with 5 bits per channel, so memory does not grow
+// with the number of frames. The bins are split into boxes along the channel
+// with the widest range at the median pixel until there are enough boxes, and
+// each box contributes the mean color of its pixels.
+// Source: https://en.wikipedia.org/wiki/Median_cut
+func medianCutPalette(canvases []*Canvas, size int) color.Palette {
+	const binsPerChannel = 1 << histogramBits
+	const shift = 8 - histogramBits
+
+	counts := make([]int, binsPerChannel*binsPerChannel*binsPerChannel)
+	sums := make([][3]int, len(counts))
+
+	for _, canvas := range canvases {
+		for i := 0; i < canvas.width; i++ {
+			for j := 0; j < canvas.height; j++ {
+				r, g, b := canvas.R[i][j], canvas.G[i][j], canvas.B[i][j]
+				bin := (int(r>>shift)*binsPerChannel+int(g>>shift))*binsPerChannel + int(b>>shift)
+
+				counts[bin]++
+				sums[bin][0] += int(r)
+				sums[bin][1] += int(g)
+				sums[bin][2] += int(b)
+			}
+		}
+	}
+
+	var bins []colorBin
+	for i, count := range counts {
+		if count > 0 {
+			bins = append(bins, colorBin{
+				color: [3]uint8{uint8(sums[i][0] / count), uint8(sums[i][1] / count), uint8(sums[i][2] / count)},
+				count: count,
+				sum:   sums[i],
+			})
+		}
+	}
+
+	boxes := [][]colorBin{bins}
+
+	for len(boxes) < size {
+		// split the box with the widest channel range
+		widest, channel, widestRange := -1, 0, 0
+
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+
+			c, r := widestChannel(box)
+			if r > widestRange {
+				widest, channel, widestRange = i, c, r
+			}
+		}
+
+		if widest < 0 {
+			break
+		}
+
+		box := boxes[widest]
+		sort.Slice(box, func(i, j int) bool {
+			return box[i].color[channel] < box[j].color[channel]
+		})
+
+		split := medianBin(box)
+		boxes[widest] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+
+	palette := make(color.Palette, 0, len(boxes))
+
+	for _, box := range boxes {
+		var sum [3]int
+		count := 0
+
+		for _, bin := range box {
+			sum[0] += bin.sum[0]
+			sum[1] += bin.sum[1]
+			sum[2] += bin.sum[2]
+			count += bin.count
+		}
+
+		palette = append(palette, color.RGBA{
+			R: uint8(sum[0] / count),
+			G: uint8(sum[1] / count),
+			B: uint8(sum[2] / count),
+			A: 255,
+		})
+	}
+
+	return palette
+}
+
+// Get the index of the first bin after the median pixel of sorted bins. Both
+// halves contain at least one bin.
+func medianBin(bins []colorBin) int {
+	total := 0
+	for _, bin := range bins {
+		total += bin.count
+	}
+
+	count := 0
+	for i, bin := range bins[:len(bins)-1] {
+		count += bin.count
+		if 2*count >= total {
+			return i + 1
+		}
+	}
+
+	return len(bins) - 1
+}
+
+// Get the channel with the widest range of values in the bins, and the range.
+func widestChannel(bins []colorBin) (int, int) {
+	min := bins[0].color
+	max := bins[0].color
+
+	for _, bin := range bins[1:] {
+		for channel := 0; channel < 3; channel++ {
+			if bin.color[channel] < min[channel] {
+				min[channel] = bin.color[channel]
+			}
+
+			if bin.color[channel] > max[channel] {
+				max[channel] = bin.color[channel]
+			}
+		}
+	}
+
+	widest := 0
+	for channel := 1; channel < 3; channel++ {
+		if int(max[channel])-int(min[channel]) > int(max[widest])-int(min[widest]) {
+			widest = channel
+		}
+	}
+
+	return widest, int(max[widest]) - int(min[widest])
+}
@@ -0,0 +1,72 @@
+package canvas
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Image with floating point colors, e.g. read from a high dynamic range file.
+type HdrImage struct {
+	width  int
+	height int
+	// pixels row by row, starting at the top left
+	pixels []FloatColor
+}
+
+// Create a black image with the specified width and height.
+func NewHdrImage(width, height int) *HdrImage {
+	if width <= 0 || height <= 0 {
+		panic("image width and height must be greater than 0")
+	}
+
+	return &HdrImage{
+		width:  width,
+		height: height,
+		pixels: make([]FloatColor, width*height),
+	}
+}
+
+// Read a Radiance HDR (.hdr, .pic) or portable float map (.pfm) file, depending
+// on the file extension.
+func ReadHdrImage(path string) (*HdrImage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".hdr", ".pic":
+		return readRadiance(reader)
+	case ".pfm":
+		return readPfm(reader)
+	}
+
+	return nil, fmt.Errorf("unsupported image format %q", filepath.Ext(path))
+}
+
+// Get image width in pixels.
+func (img *HdrImage) Width() int {
+	return img.width
+}
+
+// Get image height in pixels.
+func (img *HdrImage) Height() int {
+	return img.height
+}
+
+// Get the color of the pixel (x, y), where (0, 0) is the top left pixel.
+func (img *HdrImage) At(x, y int) FloatColor {
+	return img.pixels[y*img.width+x]
+}
+
+// Set the color of the pixel (x, y), where (0, 0) is the top left pixel.
+func (img *HdrImage) Set(x, y int, color FloatColor) {
+	img.pixels[y*img.width+x] = color
+}
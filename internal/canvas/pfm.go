@@ -0,0 +1,73 @@
+package canvas
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Read an image in the portable float map format, either with three channels
+// ("PF") or a single gray channel ("Pf").
+// Source: https://www.pauldebevec.com/Research/HDR/PFM/
+func readPfm(reader *bufio.Reader) (*HdrImage, error) {
+	var magic string
+	var width, height int
+	var scale float64
+
+	if _, err := fmt.Fscan(reader, &magic, &width, &height, &scale); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	channels := 0
+	switch magic {
+	case "PF":
+		channels = 3
+	case "Pf":
+		channels = 1
+	default:
+		return nil, errors.New("missing PFM signature")
+	}
+
+	if width <= 0 || height <= 0 || scale == 0 {
+		return nil, fmt.Errorf("invalid header (%dx%d, scale %g)", width, height, scale)
+	}
+
+	// a single whitespace character separates the header from the data
+	if _, err := reader.ReadByte(); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	// the sign of the scale gives the byte order
+	var order binary.ByteOrder = binary.BigEndian
+	if scale < 0 {
+		order = binary.LittleEndian
+	}
+
+	img := NewHdrImage(width, height)
+	row := make([]byte, 4*channels*width)
+
+	// rows are stored from bottom to top
+	for y := height - 1; y >= 0; y-- {
+		if _, err := io.ReadFull(reader, row); err != nil {
+			return nil, fmt.Errorf("failed to read row %d: %w", y, err)
+		}
+
+		for x := 0; x < width; x++ {
+			var values [3]float64
+			for c := 0; c < channels; c++ {
+				values[c] = float64(math.Float32frombits(order.Uint32(row[4*(channels*x+c):])))
+			}
+
+			if channels == 1 {
+				values[1], values[2] = values[0], values[0]
+			}
+
+			img.Set(x, y, FloatColor{values[0], values[1], values[2]})
+		}
+	}
+
+	return img, nil
+}
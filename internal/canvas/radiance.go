@@ -0,0 +1,150 @@
+package canvas
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Read an image in the Radiance RGBE format with flat or run-length encoded
+// scanlines. Only the standard orientation (-Y height +X width) is supported.
+// Source: https://www.graphics.cornell.edu/~bjw/rgbe.html
+func readRadiance(reader *bufio.Reader) (*HdrImage, error) {
+	magic, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	if !strings.HasPrefix(magic, "#?") {
+		return nil, errors.New("missing Radiance signature")
+	}
+
+	// header lines end with an empty line
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read header: %w", err)
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+
+		format, isFormat := strings.CutPrefix(line, "FORMAT=")
+		if isFormat && format != "32-bit_rle_rgbe" {
+			return nil, fmt.Errorf("unsupported pixel format %q", format)
+		}
+	}
+
+	var width, height int
+	resolution, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resolution: %w", err)
+	}
+
+	if _, err = fmt.Sscanf(resolution, "-Y %d +X %d", &height, &width); err != nil {
+		return nil, fmt.Errorf("unsupported resolution line %q", strings.TrimSpace(resolution))
+	}
+
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid resolution %dx%d", width, height)
+	}
+
+	img := NewHdrImage(width, height)
+	scanline := make([][4]byte, width)
+
+	for y := 0; y < height; y++ {
+		if err = readRadianceScanline(reader, scanline); err != nil {
+			return nil, fmt.Errorf("failed to read scanline %d: %w", y, err)
+		}
+
+		for x, rgbe := range scanline {
+			img.Set(x, y, rgbeToFloat(rgbe))
+		}
+	}
+
+	return img, nil
+}
+
+func readRadianceScanline(reader *bufio.Reader, scanline [][4]byte) error {
+	var first [4]byte
+	if _, err := io.ReadFull(reader, first[:]); err != nil {
+		return err
+	}
+
+	width := len(scanline)
+	encoded := first[0] == 2 && first[1] == 2 && first[2]&0x80 == 0
+
+	// short and long scanlines are never run-length encoded
+	if width < 8 || width > 0x7fff || !encoded {
+		scanline[0] = first
+		for x := 1; x < width; x++ {
+			if _, err := io.ReadFull(reader, scanline[x][:]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if int(first[2])<<8|int(first[3]) != width {
+		return errors.New("scanline width does not match image width")
+	}
+
+	// each of the four channels is encoded separately as runs of equal bytes
+	// and dumps of differing bytes
+	for channel := 0; channel < 4; channel++ {
+		for x := 0; x < width; {
+			count, err := reader.ReadByte()
+			if err != nil {
+				return err
+			}
+
+			run := count > 128
+			if run {
+				count -= 128
+			}
+
+			if count == 0 || x+int(count) > width {
+				return errors.New("invalid run length")
+			}
+
+			value, err := reader.ReadByte()
+			if err != nil {
+				return err
+			}
+
+			for i := 0; i < int(count); i++ {
+				if !run && i > 0 {
+					if value, err = reader.ReadByte(); err != nil {
+						return err
+					}
+				}
+
+				scanline[x][channel] = value
+				x++
+			}
+		}
+	}
+
+	return nil
+}
+
+// Convert a pixel with a shared exponent to floating point channels.
+func rgbeToFloat(rgbe [4]byte) FloatColor {
+	if rgbe[3] == 0 {
+		return FloatColor{}
+	}
+
+	f := math.Ldexp(1, int(rgbe[3])-(128+8))
+
+	return FloatColor{
+		R: (float64(rgbe[0]) + 0.5) * f,
+		G: (float64(rgbe[1]) + 0.5) * f,
+		B: (float64(rgbe[2]) + 0.5) * f,
+	}
+}
@@ -0,0 +1,56 @@
+package canvas
+
+// Arrangement of the left and right image of a stereo pair in one image.
+type StereoComposite int
+
+const (
+	// Left image on the left, right image on the right.
+	StereoSideBySide StereoComposite = iota
+	// Left image on top, right image on the bottom.
+	StereoOverUnder
+	// Red channel of the left image combined with the green and blue channels
+	// of the right image, for red-cyan glasses.
+	StereoAnaglyph
+)
+
+// Combine the images of the left and the right eye into one canvas. Both
+// canvases must have the same size.
+func Composite(left, right *Canvas, composite StereoComposite) *Canvas {
+	if left.width != right.width || left.height != right.height {
+		panic("stereo images must have the same size")
+	}
+
+	switch composite {
+	case StereoOverUnder:
+		result := NewCanvas(left.width, 2*left.height)
+		result.draw(left, 0, 0)
+		result.draw(right, 0, left.height)
+
+		return result
+	case StereoAnaglyph:
+		result := NewCanvas(left.width, left.height)
+
+		for x := 0; x < left.width; x++ {
+			copy(result.R[x], left.R[x])
+			copy(result.G[x], right.G[x])
+			copy(result.B[x], right.B[x])
+		}
+
+		return result
+	default:
+		result := NewCanvas(2*left.width, left.height)
+		result.draw(left, 0, 0)
+		result.draw(right, left.width, 0)
+
+		return result
+	}
+}
+
+// Copy another canvas into this one, with its top left corner at (x, y).
+func (canvas *Canvas) draw(other *Canvas, x, y int) {
+	for i := 0; i < other.width; i++ {
+		copy(canvas.R[x+i][y:], other.R[i])
+		copy(canvas.G[x+i][y:], other.G[i])
+		copy(canvas.B[x+i][y:], other.B[i])
+	}
+}
@@ -0,0 +1,214 @@
+package geometry
+
+import (
+	"context"
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+
+	"github.com/b-erhart/raytracer/internal/canvas"
+)
+
+// Options for adaptive antialiasing.
+type AdaptiveOptions struct {
+	// Total samples every pixel gets.
+	MinSamples int
+	// Total samples of pixels that need refinement.
+	MaxSamples int
+	// Pixels whose sample luminances deviate by more than this, or whose color
+	// differs from a neighbor's by more than this in any channel, are refined.
+	// Colors range from 0 to 1.
+	Threshold float64
+}
+
+// Check whether adaptive antialiasing is configured.
+func (o AdaptiveOptions) Enabled() bool {
+	return o.MaxSamples > 0
+}
+
+// Render the view into the accumulator with adaptive antialiasing. All pixels
+// get MinSamples samples first. Afterwards, only pixels whose samples or
+// neighbors differ beyond the threshold are refined up to MaxSamples. The
+// samples of both passes continue the same sequence, so the refined pixels
+// end up stratified over all of their samples. Progress is reported against
+// the total number of samples, which is known once the pixels to refine are.
+func (r *Raytracer) RenderAdaptive(ctx context.Context, view View, acc *canvas.Accumulator, options AdaptiveOptions, progress func(Progress)) error {
+	if options.MinSamples <= 0 || options.MaxSamples < options.MinSamples {
+		panic("adaptive antialiasing requires 0 < MinSamples <= MaxSamples")
+	}
+
+	width, height := acc.Width(), acc.Height()
+	status := &adaptiveProgress{
+		start:    time.Now(),
+		callback: progress,
+		passes:   2,
+		budget:   int64(width * height * options.MaxSamples),
+	}
+
+	if options.MaxSamples == options.MinSamples {
+		status.passes = 1
+	}
+
+	err := r.renderTiles(ctx, width, height, 0, status.update, func(w *worker, tile Tile) {
+		for j := tile.Y; j < tile.Y+tile.Height; j++ {
+			for i := tile.X; i < tile.X+tile.Width; i++ {
+				r.samplePixel(w, view, acc, i, j, 0, options.MinSamples)
+			}
+		}
+
+		status.samples.Add(int64(tile.Width * tile.Height * options.MinSamples))
+	})
+	if err != nil || options.MaxSamples == options.MinSamples {
+		return err
+	}
+
+	refine, refined := refinementMask(acc, options.Threshold)
+	status.finishPass(int64(width*height*options.MinSamples + refined*(options.MaxSamples-options.MinSamples)))
+
+	return r.renderTiles(ctx, width, height, 1, status.update, func(w *worker, tile Tile) {
+		count := 0
+
+		for j := tile.Y; j < tile.Y+tile.Height; j++ {
+			for i := tile.X; i < tile.X+tile.Width; i++ {
+				if refine[j*width+i] {
+					r.samplePixel(w, view, acc, i, j, options.MinSamples, options.MaxSamples)
+					count++
+				}
+			}
+		}
+
+		status.samples.Add(int64(count * (options.MaxSamples - options.MinSamples)))
+	})
+}
+
+// Add the samples with numbers first to last-1 to the pixel at (x, y).
+// Stratified samples are taken from a scrambled Sobol sequence, whose
+// samples are well distributed for any number of them, so later samples
+// continue the stratification of the earlier ones.
+func (r *Raytracer) samplePixel(w *worker, view View, acc *canvas.Accumulator, x, y, first, last int) {
+	scramble := uint64(hashInts(r.renderOptions.Seed, x, y))
+
+	for s := first; s < last; s++ {
+		dx, dy := w.rng.Float64()-0.5, w.rng.Float64()-0.5
+		if r.antialiasing.Sampling == SamplingStratified {
+			dx, dy = sobolOffset(uint32(s), uint32(scramble), uint32(scramble>>32))
+		}
+
+		r.addSample(w, view, acc, x, y, dx, dy)
+	}
+}
+
+// Progress of an adaptive render. The passes take different numbers of
+// samples per tile, so the progress is measured in samples.
+type adaptiveProgress struct {
+	start    time.Time
+	callback func(Progress)
+	passes   int
+	// samples of the completed tiles
+	samples atomic.Int64
+	// total number of samples, which is an upper bound until the pixels to
+	// refine are known
+	budget int64
+	// status of the current pass and the sums of the completed passes
+	pass      Progress
+	completed Progress
+}
+
+func (a *adaptiveProgress) update(pass Progress) {
+	a.pass = pass
+
+	if a.callback == nil {
+		return
+	}
+
+	status := Progress{
+		CompletedTiles: a.completed.CompletedTiles + pass.CompletedTiles,
+		TotalTiles:     pass.TotalTiles * a.passes,
+		Rays:           a.completed.Rays + pass.Rays,
+		Elapsed:        time.Since(a.start),
+	}
+
+	samples := a.samples.Load()
+	status.Percent = 100 * float64(samples) / float64(a.budget)
+
+	if status.Elapsed > 0 {
+		status.RaysPerSecond = float64(status.Rays) / status.Elapsed.Seconds()
+	}
+
+	if samples > 0 {
+		status.ETA = time.Duration(float64(status.Elapsed) * float64(a.budget-samples) / float64(samples))
+	}
+
+	a.callback(status)
+}
+
+// Add the current pass to the completed ones and set the final budget.
+func (a *adaptiveProgress) finishPass(budget int64) {
+	a.completed.CompletedTiles += a.pass.CompletedTiles
+	a.completed.Rays += a.pass.Rays
+	a.pass = Progress{}
+	a.budget = budget
+}
+
+// Mark the pixels whose samples deviate, or whose color differs from one of
+// their neighbors, by more than the threshold, and count them.
+func refinementMask(acc *canvas.Accumulator, threshold float64) ([]bool, int) {
+	width, height := acc.Width(), acc.Height()
+	mask := make([]bool, width*height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if acc.Deviation(x, y) > threshold {
+				mask[y*width+x] = true
+			}
+
+			// comparing with the right and upper neighbor covers every pair once
+			color := acc.Color(x, y)
+
+			if x+1 < width && colorDifference(color, acc.Color(x+1, y)) > threshold {
+				mask[y*width+x] = true
+				mask[y*width+x+1] = true
+			}
+
+			if y+1 < height && colorDifference(color, acc.Color(x, y+1)) > threshold {
+				mask[y*width+x] = true
+				mask[(y+1)*width+x] = true
+			}
+		}
+	}
+
+	count := 0
+	for _, refine := range mask {
+		if refine {
+			count++
+		}
+	}
+
+	return mask, count
+}
+
+func colorDifference(a, b canvas.FloatColor) float64 {
+	return math.Max(math.Abs(a.R-b.R), math.Max(math.Abs(a.G-b.G), math.Abs(a.B-b.B)))
+}
+
+// Get the offset from the pixel center of sample number index of the
+// two-dimensional Sobol sequence, scrambled by xoring its coordinates with
+// the given bits. Any power of two of consecutive samples, starting at a
+// multiple of it, is stratified over the pixel. Offsets range from -0.5 to
+// 0.5.
+// Source: Kollig and Keller, "Efficient Multidimensional Sampling" (2002)
+func sobolOffset(index, scrambleX, scrambleY uint32) (float64, float64) {
+	x := bits.Reverse32(index) ^ scrambleX
+
+	y := scrambleY
+	for v := uint32(1 << 31); index != 0; index >>= 1 {
+		if index&1 != 0 {
+			y ^= v
+		}
+
+		v ^= v >> 1
+	}
+
+	return float64(x)/(1<<32) - 0.5, float64(y)/(1<<32) - 0.5
+}
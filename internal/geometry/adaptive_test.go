@@ -0,0 +1,28 @@
+package geometry
+
+import "testing"
+
+func TestSobolOffsetStratifiesPowersOfTwo(t *testing.T) {
+	for _, scramble := range []int64{0, 1, 42, hashInts(7, 3, 5)} {
+		// 4 samples fill a 2x2 grid, 16 samples a 4x4 grid and so on, starting
+		// at any multiple of the number of samples
+		for _, strata := range []int{2, 4, 8} {
+			count := strata * strata
+
+			for first := 0; first < 4*count; first += count {
+				cells := make([]bool, count)
+
+				for s := first; s < first+count; s++ {
+					dx, dy := sobolOffset(uint32(s), uint32(scramble), uint32(uint64(scramble)>>32))
+					cell := int((dy+0.5)*float64(strata))*strata + int((dx+0.5)*float64(strata))
+
+					if cells[cell] {
+						t.Fatalf("scramble %d: samples %d to %d put two samples into cell %d of %dx%d", scramble, first, first+count-1, cell, strata, strata)
+					}
+
+					cells[cell] = true
+				}
+			}
+		}
+	}
+}
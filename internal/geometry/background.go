@@ -0,0 +1,83 @@
+package geometry
+
+import (
+	"math"
+
+	"github.com/b-erhart/raytracer/internal/canvas"
+)
+
+// Background of the image, seen by camera rays that do not hit any object.
+// Reflections and refractions do not see it. An image takes precedence over a
+// gradient, which takes precedence over the flat color.
+type Background struct {
+	Color canvas.Color
+	// Color stops of a vertical gradient over the frame, sorted by position.
+	Gradient []GradientStop
+	// Backplate image, which is stretched to the frame.
+	Image *canvas.Canvas
+}
+
+// Color at a position of a vertical gradient.
+type GradientStop struct {
+	// Position from 0 (top of the frame) to 1 (bottom of the frame).
+	Position float64
+	Color    canvas.Color
+}
+
+// Get the background color at the point (x, y) of the frame, where (0, 0) is
+// the top left and (1, 1) the bottom right corner.
+func (b Background) At(x, y float64) canvas.Color {
+	if b.Image != nil {
+		return b.imageAt(x, y)
+	}
+
+	if len(b.Gradient) == 0 {
+		return b.Color
+	}
+
+	if y <= b.Gradient[0].Position {
+		return b.Gradient[0].Color
+	}
+
+	for i := 1; i < len(b.Gradient); i++ {
+		from, to := b.Gradient[i-1], b.Gradient[i]
+		if y < to.Position {
+			return from.Color.Merge(to.Color, (y-from.Position)/(to.Position-from.Position))
+		}
+	}
+
+	return b.Gradient[len(b.Gradient)-1].Color
+}
+
+// Check whether the background varies over the frame.
+func (b Background) custom() bool {
+	return b.Image != nil || len(b.Gradient) > 0
+}
+
+// Get the bilinearly interpolated color of the image at a point of the frame.
+func (b Background) imageAt(x, y float64) canvas.Color {
+	img := b.Image
+	width, height := img.Width(), img.Height()
+
+	px := math.Max(0, math.Min(1, x)) * float64(width-1)
+	py := math.Max(0, math.Min(1, y)) * float64(height-1)
+
+	x0, y0 := int(px), int(py)
+	x1, y1 := x0, y0
+	if x1 < width-1 {
+		x1++
+	}
+	if y1 < height-1 {
+		y1++
+	}
+
+	fx, fy := px-float64(x0), py-float64(y0)
+	at := func(i, j int) canvas.FloatColor {
+		return canvas.Color{R: img.R[i][j], G: img.G[i][j], B: img.B[i][j]}.Float()
+	}
+
+	top := at(x0, y0).Scale(1 - fx).Add(at(x1, y0).Scale(fx))
+	bottom := at(x0, y1).Scale(1 - fx).Add(at(x1, y1).Scale(fx))
+
+	return top.Scale(1 - fy).Add(bottom.Scale(fy)).Color()
+}
@@ -0,0 +1,162 @@
+package geometry
+
+import "math"
+
+type sahBin struct {
+	box   bvhBoundingBox
+	count int
+}
+
+// Find the cheapest split of the objects according to the binned surface area
+// heuristic and partition them accordingly. Return the number of objects that
+// go into the left child and the split axis, or whether creating a leaf is
+// cheaper.
+// Source: https://www.sci.utah.edu/~wald/Publications/2007/ParallelBVHBuild/fastbuild.pdf
+func sahSplit(objs []Object, box bvhBoundingBox, options BvhOptions) (int, int, bool) {
+	centroidBox := calculateCentroidBox(objs)
+	parentArea := box.surfaceArea()
+	if parentArea <= 0 {
+		parentArea = 1
+	}
+
+	bestCost := math.Inf(1)
+	bestAxis := -1
+	bestBin := 0
+
+	bins := make([]sahBin, options.Bins)
+	rightAreas := make([]float64, options.Bins)
+	rightCounts := make([]int, options.Bins)
+
+	for axis := 0; axis < 3; axis++ {
+		low, high := centroidBox.axisRange(axis)
+		if high-low <= 0 {
+			continue
+		}
+
+		for i := range bins {
+			bins[i] = sahBin{}
+		}
+
+		for _, obj := range objs {
+			bin := &bins[binIndex(objectCentroid(obj, axis), low, high, options.Bins)]
+			if bin.count == 0 {
+				bin.box = bvhBoundingBox(obj.extremes())
+			} else {
+				bin.box = bvhBoundingBox(merge(extremes(bin.box), obj.extremes()))
+			}
+			bin.count++
+		}
+
+		// sweep from the right to get the area and object count right of each split
+		var rightBox bvhBoundingBox
+		rightCount := 0
+		for i := options.Bins - 1; i > 0; i-- {
+			rightBox, rightCount = mergeBin(rightBox, rightCount, bins[i])
+			rightAreas[i] = rightBox.surfaceArea()
+			rightCounts[i] = rightCount
+		}
+
+		// sweep from the left and evaluate the cost of splitting before bin i
+		var leftBox bvhBoundingBox
+		leftCount := 0
+		for i := 1; i < options.Bins; i++ {
+			leftBox, leftCount = mergeBin(leftBox, leftCount, bins[i-1])
+			if leftCount == 0 || rightCounts[i] == 0 {
+				continue
+			}
+
+			cost := options.TraversalCost + options.IntersectionCost*
+				(leftBox.surfaceArea()*float64(leftCount)+rightAreas[i]*float64(rightCounts[i]))/parentArea
+
+			if cost < bestCost {
+				bestCost = cost
+				bestAxis = axis
+				bestBin = i
+			}
+		}
+	}
+
+	leafCost := options.IntersectionCost * float64(len(objs))
+
+	// all centroids coincide, so there is no meaningful split
+	if bestAxis < 0 {
+		if len(objs) <= options.MaxLeafSize {
+			return 0, 0, true
+		}
+
+		return len(objs) / 2, 0, false
+	}
+
+	if len(objs) <= options.MaxLeafSize && leafCost <= bestCost {
+		return 0, 0, true
+	}
+
+	low, high := centroidBox.axisRange(bestAxis)
+	elementsLeft := 0
+
+	for i := range objs {
+		if binIndex(objectCentroid(objs[i], bestAxis), low, high, options.Bins) < bestBin {
+			objs[i], objs[elementsLeft] = objs[elementsLeft], objs[i]
+			elementsLeft++
+		}
+	}
+
+	return elementsLeft, bestAxis, false
+}
+
+func mergeBin(box bvhBoundingBox, count int, bin sahBin) (bvhBoundingBox, int) {
+	switch {
+	case bin.count == 0:
+		return box, count
+	case count == 0:
+		return bin.box, bin.count
+	default:
+		return bvhBoundingBox(merge(extremes(box), extremes(bin.box))), count + bin.count
+	}
+}
+
+func binIndex(centroid, low, high float64, bins int) int {
+	index := int(float64(bins) * (centroid - low) / (high - low))
+
+	if index >= bins {
+		return bins - 1
+	} else if index < 0 {
+		return 0
+	}
+
+	return index
+}
+
+func calculateCentroidBox(objs []Object) bvhBoundingBox {
+	box := bvhBoundingBox{
+		minX: math.Inf(1),
+		minY: math.Inf(1),
+		minZ: math.Inf(1),
+		maxX: math.Inf(-1),
+		maxY: math.Inf(-1),
+		maxZ: math.Inf(-1),
+	}
+
+	for _, obj := range objs {
+		x, y, z := objectCentroid(obj, 0), objectCentroid(obj, 1), objectCentroid(obj, 2)
+		box = bvhBoundingBox(merge(extremes(box), extremes{x, y, z, x, y, z}))
+	}
+
+	return box
+}
+
+func objectCentroid(obj Object, axis int) float64 {
+	low, high := bvhBoundingBox(obj.extremes()).axisRange(axis)
+	return (low + high) / 2
+}
+
+func (b bvhBoundingBox) axisRange(axis int) (float64, float64) {
+	switch axis {
+	case 0:
+		return b.minX, b.maxX
+	case 1:
+		return b.minY, b.maxY
+	default:
+		return b.minZ, b.maxZ
+	}
+}
@@ -0,0 +1,65 @@
+package geometry
+
+import "fmt"
+
+// Quality metrics of a bounding volume hierarchy.
+type BvhStats struct {
+	// Number of levels below the root.
+	Depth int
+	// Number of inner nodes.
+	Nodes int
+	// Number of leaves.
+	Leaves int
+	// Number of objects in all leaves.
+	Objects int
+	// Largest number of objects in a single leaf.
+	MaxLeafObjects int
+	// Expected cost of tracing a ray that hits the root box, according to the
+	// cost model the hierarchy was built with.
+	SAHCost float64
+}
+
+func (s BvhStats) String() string {
+	return fmt.Sprintf(
+		"depth %d, %d nodes, %d leaves, %d objects (max. %d per leaf), SAH cost %.2f",
+		s.Depth, s.Nodes, s.Leaves, s.Objects, s.MaxLeafObjects, s.SAHCost,
+	)
+}
+
+// Get the quality metrics of the tree.
+func (t BvhTree) Stats() BvhStats {
+	var stats BvhStats
+	stats.SAHCost = t.root.collectStats(&stats, 0, t.options)
+
+	return stats
+}
+
+func (n *bvhTreeNode) collectStats(stats *BvhStats, depth int, options BvhOptions) float64 {
+	stats.Nodes++
+
+	costLeft := n.left.collectStats(stats, depth+1, options)
+	costRight := n.right.collectStats(stats, depth+1, options)
+
+	area := n.box.surfaceArea()
+	if area <= 0 {
+		return options.TraversalCost + costLeft + costRight
+	}
+
+	return options.TraversalCost +
+		(n.left.boundingBox().surfaceArea()*costLeft+n.right.boundingBox().surfaceArea()*costRight)/area
+}
+
+func (l *bvhTreeLeaf) collectStats(stats *BvhStats, depth int, options BvhOptions) float64 {
+	stats.Leaves++
+	stats.Objects += len(l.objs)
+
+	if depth > stats.Depth {
+		stats.Depth = depth
+	}
+
+	if len(l.objs) > stats.MaxLeafObjects {
+		stats.MaxLeafObjects = len(l.objs)
+	}
+
+	return options.IntersectionCost * float64(len(l.objs))
+}
@@ -8,7 +8,8 @@ import (
 
 // Bounding Volume Tree
 type BvhTree struct {
-	root bvhTreeElement
+	root    bvhTreeElement
+	options BvhOptions
 }
 
 func (t BvhTree) String() string {
@@ -16,11 +17,15 @@ func (t BvhTree) String() string {
 }
 
 type bvhTreeElement interface {
-	getRelevantObjects(ray Ray) []Object
+	closestHit(ray Ray, inverseDir Vector, tMax float64, closest *Hit) float64
+	anyHit(ray Ray, inverseDir Vector, tMax float64, accept func(Hit) bool) bool
+	collectStats(stats *BvhStats, depth int, options BvhOptions) float64
+	boundingBox() bvhBoundingBox
 }
 
 type bvhTreeNode struct {
 	box   bvhBoundingBox
+	axis  int
 	left  bvhTreeElement
 	right bvhTreeElement
 }
@@ -49,13 +54,49 @@ func (l *bvhTreeLeaf) String() string {
 
 type bvhBoundingBox extremes
 
-func ConstructBvhTree(objs []Object) BvhTree {
+// Strategy for splitting the objects of a node of a bounding volume hierarchy.
+type BvhSplit int
+
+const (
+	// Binned surface area heuristic.
+	SplitSAH BvhSplit = iota
+	// Split at the median object along the longest axis of the node.
+	SplitMedian
+)
+
+// Options for constructing a bounding volume hierarchy.
+type BvhOptions struct {
+	Split BvhSplit
+	// Maximum number of objects in a leaf. With the surface area heuristic,
+	// leaves with fewer objects are still split if it is cheaper.
+	MaxLeafSize int
+	// Number of bins per axis evaluated by the surface area heuristic.
+	Bins int
+	// Estimated cost of traversing a node.
+	TraversalCost float64
+	// Estimated cost of intersecting a ray with an object.
+	IntersectionCost float64
+}
+
+// Get the default options for constructing a bounding volume hierarchy.
+func DefaultBvhOptions() BvhOptions {
+	return BvhOptions{
+		Split:            SplitSAH,
+		MaxLeafSize:      4,
+		Bins:             16,
+		TraversalCost:    1,
+		IntersectionCost: 1,
+	}
+}
+
+func ConstructBvhTree(objs []Object, options BvhOptions) BvhTree {
 	return BvhTree{
-		root: constructElement(objs),
+		root:    constructElement(objs, options),
+		options: options,
 	}
 }
 
-func constructElement(objs []Object) bvhTreeElement {
+func constructElement(objs []Object, options BvhOptions) bvhTreeElement {
 	box := calculateBoundingBox(objs)
 
 	if len(objs) <= 1 {
@@ -65,31 +106,65 @@ func constructElement(objs []Object) bvhTreeElement {
 		}
 	}
 
+	var elementsLeft, axis int
+
+	switch options.Split {
+	case SplitMedian:
+		if len(objs) <= options.MaxLeafSize {
+			return &bvhTreeLeaf{
+				box:  box,
+				objs: objs,
+			}
+		}
+
+		elementsLeft, axis = medianSplit(objs, box)
+	default:
+		var createLeaf bool
+		elementsLeft, axis, createLeaf = sahSplit(objs, box, options)
+
+		if createLeaf {
+			return &bvhTreeLeaf{
+				box:  box,
+				objs: objs,
+			}
+		}
+	}
+
+	objsLeft := objs[0:elementsLeft]
+	objsRight := objs[elementsLeft:]
+
+	return &bvhTreeNode{
+		box:   box,
+		axis:  axis,
+		left:  constructElement(objsLeft, options),
+		right: constructElement(objsRight, options),
+	}
+}
+
+// Sort the objects along the longest axis of the box and return the number of
+// objects that go into the left child as well as the axis.
+func medianSplit(objs []Object, box bvhBoundingBox) (int, int) {
+	var axis int
+
 	switch {
 	case box.xDiff() >= box.yDiff() && box.xDiff() >= box.zDiff():
 		sort.Slice(objs, func(i, j int) bool {
 			return objs[i].extremes().maxX < objs[j].extremes().maxX
 		})
+		axis = 0
 	case box.yDiff() >= box.xDiff() && box.yDiff() >= box.zDiff():
 		sort.Slice(objs, func(i, j int) bool {
 			return objs[i].extremes().maxY < objs[j].extremes().maxY
 		})
+		axis = 1
 	default:
 		sort.Slice(objs, func(i, j int) bool {
 			return objs[i].extremes().maxZ < objs[j].extremes().maxZ
 		})
+		axis = 2
 	}
 
-	elementsLeft := len(objs) - len(objs)/2
-
-	objsLeft := objs[0:elementsLeft]
-	objsRight := objs[elementsLeft:]
-
-	return &bvhTreeNode{
-		box:   box,
-		left:  constructElement(objsLeft),
-		right: constructElement(objsRight),
-	}
+	return len(objs) - len(objs)/2, axis
 }
 
 func calculateBoundingBox(objs []Object) bvhBoundingBox {
@@ -106,58 +181,143 @@ func calculateBoundingBox(objs []Object) bvhBoundingBox {
 	return bvhBoundingBox(box)
 }
 
-func (t BvhTree) GetRelevantObjects(ray Ray) []Object {
-	return t.root.getRelevantObjects(ray)
+func (n *bvhTreeNode) boundingBox() bvhBoundingBox {
+	return n.box
+}
+
+func (l *bvhTreeLeaf) boundingBox() bvhBoundingBox {
+	return l.box
 }
 
-func (n *bvhTreeNode) getRelevantObjects(ray Ray) []Object {
-	if !n.box.intersects(ray) {
-		return []Object{}
+// Find the closest object hit by the ray with a ray parameter below tMax.
+// Children are visited front to back and boxes behind the closest hit found so
+// far are skipped.
+func (t BvhTree) ClosestHit(ray Ray, tMax float64) (Hit, bool) {
+	var closest Hit
+
+	inverseDir := inverseDirection(ray.Direction)
+
+	if _, intersects := t.root.boundingBox().entry(ray.Origin, inverseDir, tMax); !intersects {
+		return closest, false
 	}
 
-	objsLeft := n.left.getRelevantObjects(ray)
-	objsRight := n.right.getRelevantObjects(ray)
+	t.root.closestHit(ray, inverseDir, tMax, &closest)
+
+	return closest, closest.Object != nil
+}
 
-	ret := append(objsLeft[:len(objsLeft):len(objsLeft)], objsRight...)
+func (n *bvhTreeNode) closestHit(ray Ray, inverseDir Vector, tMax float64, closest *Hit) float64 {
+	first, second := n.left, n.right
+	tFirst, hitsFirst := first.boundingBox().entry(ray.Origin, inverseDir, tMax)
+	tSecond, hitsSecond := second.boundingBox().entry(ray.Origin, inverseDir, tMax)
+
+	if hitsSecond && (!hitsFirst || tSecond < tFirst) {
+		first, second = second, first
+		tFirst, tSecond = tSecond, tFirst
+		hitsFirst, hitsSecond = hitsSecond, hitsFirst
+	}
+
+	if hitsFirst {
+		tMax = first.closestHit(ray, inverseDir, tMax, closest)
+	}
+
+	if hitsSecond && tSecond < tMax {
+		tMax = second.closestHit(ray, inverseDir, tMax, closest)
+	}
 
-	return ret
+	return tMax
 }
 
-func (l *bvhTreeLeaf) getRelevantObjects(ray Ray) []Object {
-	if !l.box.intersects(ray) {
-		return []Object{}
+func (l *bvhTreeLeaf) closestHit(ray Ray, inverseDir Vector, tMax float64, closest *Hit) float64 {
+	for _, obj := range l.objs {
+		if hit, intersects := obj.hit(ray, tMax); intersects {
+			hit.Root = obj
+			*closest = hit
+			tMax = hit.Distance
+		}
 	}
 
-	return l.objs
+	return tMax
 }
 
-// source: https://tavianator.com/2011/ray_box.html
-func (b bvhBoundingBox) intersects(ray Ray) bool {
-	inverseRayDir := Vector{
-		X: 1 / ray.Direction.Normalize().X,
-		Y: 1 / ray.Direction.Normalize().Y,
-		Z: 1 / ray.Direction.Normalize().Z,
+// Check whether the ray hits any object with a ray parameter below maxDistance.
+// Traversal stops at the first hit found, which is not necessarily the closest.
+func (t BvhTree) Occluded(ray Ray, maxDistance float64) bool {
+	return t.AnyHit(ray, maxDistance, func(Hit) bool {
+		return true
+	})
+}
+
+// Call accept for hits of the ray with a ray parameter below tMax, in no
+// particular order, until it returns true. Return whether a hit was accepted.
+func (t BvhTree) AnyHit(ray Ray, tMax float64, accept func(Hit) bool) bool {
+	inverseDir := inverseDirection(ray.Direction)
+
+	if _, intersects := t.root.boundingBox().entry(ray.Origin, inverseDir, tMax); !intersects {
+		return false
 	}
 
-	tx1 := (b.minX - ray.Origin.X) * inverseRayDir.X
-	tx2 := (b.maxX - ray.Origin.X) * inverseRayDir.X
+	return t.root.anyHit(ray, inverseDir, tMax, accept)
+}
 
-	tmin := math.Min(tx1, tx2)
-	tmax := math.Max(tx1, tx2)
+func (n *bvhTreeNode) anyHit(ray Ray, inverseDir Vector, tMax float64, accept func(Hit) bool) bool {
+	if _, intersects := n.left.boundingBox().entry(ray.Origin, inverseDir, tMax); intersects && n.left.anyHit(ray, inverseDir, tMax, accept) {
+		return true
+	}
 
-	ty1 := (b.minY - ray.Origin.Y) * inverseRayDir.Y
-	ty2 := (b.maxY - ray.Origin.Y) * inverseRayDir.Y
+	_, intersects := n.right.boundingBox().entry(ray.Origin, inverseDir, tMax)
 
-	tmin = math.Max(tmin, math.Min(ty1, ty2))
-	tmax = math.Min(tmax, math.Max(ty1, ty2))
+	return intersects && n.right.anyHit(ray, inverseDir, tMax, accept)
+}
 
-	tz1 := (b.minZ - ray.Origin.Z) * inverseRayDir.Z
-	tz2 := (b.maxZ - ray.Origin.Z) * inverseRayDir.Z
+func (l *bvhTreeLeaf) anyHit(ray Ray, inverseDir Vector, tMax float64, accept func(Hit) bool) bool {
+	for _, obj := range l.objs {
+		if obj.anyHit(ray, tMax, accept) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func inverseDirection(direction Vector) Vector {
+	return Vector{
+		X: 1 / direction.X,
+		Y: 1 / direction.Y,
+		Z: 1 / direction.Z,
+	}
+}
+
+// Get the ray parameter at which a ray enters the box (0 if it starts inside)
+// and whether this happens before tMax.
+// source: https://tavianator.com/2011/ray_box.html
+func (b bvhBoundingBox) entry(origin, inverseDir Vector, tMax float64) (float64, bool) {
+	tx1 := (b.minX - origin.X) * inverseDir.X
+	tx2 := (b.maxX - origin.X) * inverseDir.X
+
+	tEnter := math.Min(tx1, tx2)
+	tExit := math.Max(tx1, tx2)
+
+	ty1 := (b.minY - origin.Y) * inverseDir.Y
+	ty2 := (b.maxY - origin.Y) * inverseDir.Y
+
+	tEnter = math.Max(tEnter, math.Min(ty1, ty2))
+	tExit = math.Min(tExit, math.Max(ty1, ty2))
 
-	tmin = math.Max(tmin, math.Min(tz1, tz2))
-	tmax = math.Min(tmax, math.Max(tz1, tz2))
+	tz1 := (b.minZ - origin.Z) * inverseDir.Z
+	tz2 := (b.maxZ - origin.Z) * inverseDir.Z
+
+	tEnter = math.Max(tEnter, math.Min(tz1, tz2))
+	tExit = math.Min(tExit, math.Max(tz1, tz2))
+
+	tEnter = math.Max(tEnter, 0)
+
+	return tEnter, tExit >= tEnter && tEnter < tMax
+}
 
-	return tmax >= math.Max(0, tmin)
+func (b bvhBoundingBox) surfaceArea() float64 {
+	x, y, z := b.xDiff(), b.yDiff(), b.zDiff()
+	return 2 * (x*y + y*z + z*x)
 }
 
 func (b bvhBoundingBox) xDiff() float64 {
@@ -0,0 +1,106 @@
+package geometry
+
+import "math"
+
+// Brown-Conrady lens distortion. Coordinates are measured on the image plane
+// relative to its center and divided by the distance of the plane from the
+// eye, like the normalized coordinates of common camera calibration tools, so
+// coefficients from a calibrated camera can be used directly.
+// Source: https://en.wikipedia.org/wiki/Distortion_(optics)#Software_correction
+type Distortion struct {
+	// Radial coefficients. Negative values give barrel, positive values
+	// pincushion distortion.
+	K1 float64
+	K2 float64
+	K3 float64
+	// Tangential coefficients, caused by a lens that is not parallel to the
+	// sensor.
+	P1 float64
+	P2 float64
+}
+
+// Number of fixed-point iterations for inverting the distortion.
+const undistortIterations = 20
+
+// Get a copy of the view whose image is distorted like a real lens would.
+func (v View) WithDistortion(distortion Distortion) View {
+	v.distortion = distortion
+
+	return v
+}
+
+// Get a copy of the view that darkens the image towards its borders. A
+// strength of 1 gives the natural falloff of a lens with the fourth power of
+// the cosine of the angle to the viewing direction, 0 disables vignetting.
+func (v View) WithVignetting(strength float64) View {
+	v.vignetting = strength
+
+	return v
+}
+
+func (v View) Distortion() Distortion {
+	return v.distortion
+}
+
+// Get the factor by which vignetting darkens the point (x, y) of the canvas.
+func (v View) Vignetting(x, y float64) float64 {
+	if v.vignetting <= 0 {
+		return 1
+	}
+
+	planeX, planeY := v.normalizedCoordinates(Add(v.bottomLeft, Add(Sprod(v.du, x), Sprod(v.dv, y))))
+	planeX, planeY = v.distortion.invert(planeX, planeY)
+
+	cos := 1 / math.Sqrt(1+planeX*planeX+planeY*planeY)
+
+	return 1 - v.vignetting*(1-math.Pow(cos, 4))
+}
+
+// Map a point of the distorted image plane to the point of the undistorted
+// plane a ray has to pass through to produce it.
+func (v View) undistort(point Vector) Vector {
+	if v.distortion == (Distortion{}) {
+		return point
+	}
+
+	x, y := v.distortion.invert(v.normalizedCoordinates(point))
+	distance := v.lookAt.Length()
+
+	return Add(Add(v.eye, v.lookAt), Add(Sprod(v.u, x*distance), Sprod(v.v, y*distance)))
+}
+
+// Get the coordinates of a point on the image plane relative to its center,
+// divided by the distance of the plane from the eye.
+func (v View) normalizedCoordinates(point Vector) (float64, float64) {
+	offset := Sub(point, Add(v.eye, v.lookAt))
+	distance := v.lookAt.Length()
+
+	return Dot(offset, v.u) / distance, Dot(offset, v.v) / distance
+}
+
+// Find the coordinates that the distortion maps to (x, y). The distortion
+//
+//	x' = x (1 + K1 r^2 + K2 r^4 + K3 r^6) + 2 P1 x y + P2 (r^2 + 2 x^2)
+//	y' = y (1 + K1 r^2 + K2 r^4 + K3 r^6) + P1 (r^2 + 2 y^2) + 2 P2 x y
+//
+// has no closed-form inverse, so the undistorted point is refined iteratively.
+func (d Distortion) invert(x, y float64) (float64, float64) {
+	undistortedX, undistortedY := x, y
+
+	for i := 0; i < undistortIterations; i++ {
+		r2 := undistortedX*undistortedX + undistortedY*undistortedY
+		radial := 1 + r2*(d.K1+r2*(d.K2+r2*d.K3))
+
+		if radial <= 0 {
+			break
+		}
+
+		tangentialX := 2*d.P1*undistortedX*undistortedY + d.P2*(r2+2*undistortedX*undistortedX)
+		tangentialY := d.P1*(r2+2*undistortedY*undistortedY) + 2*d.P2*undistortedX*undistortedY
+
+		undistortedX = (x - tangentialX) / radial
+		undistortedY = (y - tangentialY) / radial
+	}
+
+	return undistortedX, undistortedY
+}
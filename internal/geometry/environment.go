@@ -0,0 +1,147 @@
+package geometry
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/b-erhart/raytracer/internal/canvas"
+)
+
+// Equirectangular image of the surroundings of a scene, infinitely far away.
+// The top row is in the +y direction, and the center of the image is in the +z
+// direction.
+type EnvironmentMap struct {
+	image *canvas.HdrImage
+	// cumulative distribution over the rows, and over the pixels of each row,
+	// weighted by luminance and solid angle
+	rowCdf    []float64
+	columnCdf [][]float64
+}
+
+// Lighting by an environment map.
+type Environment struct {
+	// Environment map seen by rays that do not hit any object. Nil disables
+	// the environment.
+	Map *EnvironmentMap
+	// Rotation of the map around the y axis in radians.
+	Rotation float64
+	// Factor for the radiance of the map.
+	Intensity float64
+	// Number of importance sampled directions for lighting each shading
+	// point. Zero only shows the map in the background and in reflections.
+	Samples int
+}
+
+// Check whether an environment map is configured.
+func (e Environment) Enabled() bool {
+	return e.Map != nil
+}
+
+// Create an environment map from an equirectangular image, and prepare
+// sampling its bright areas.
+func NewEnvironmentMap(image *canvas.HdrImage) *EnvironmentMap {
+	width, height := image.Width(), image.Height()
+	env := &EnvironmentMap{
+		image:     image,
+		rowCdf:    make([]float64, height+1),
+		columnCdf: make([][]float64, height),
+	}
+
+	for y := 0; y < height; y++ {
+		// rows near the poles cover less solid angle
+		sinTheta := math.Sin(math.Pi * (float64(y) + 0.5) / float64(height))
+
+		env.columnCdf[y] = make([]float64, width+1)
+		for x := 0; x < width; x++ {
+			env.columnCdf[y][x+1] = env.columnCdf[y][x] + image.At(x, y).Luminance()*sinTheta
+		}
+
+		env.rowCdf[y+1] = env.rowCdf[y] + env.columnCdf[y][width]
+	}
+
+	return env
+}
+
+// Get the radiance arriving from a direction.
+func (e Environment) Radiance(direction Vector) canvas.FloatColor {
+	x, y := e.pixel(direction)
+
+	return e.Map.image.At(x, y).Scale(e.Intensity)
+}
+
+// Get the pixel of the map in a direction.
+func (e Environment) pixel(direction Vector) (int, int) {
+	d := direction.Normalize()
+	width, height := e.Map.image.Width(), e.Map.image.Height()
+
+	u := 0.5 + (math.Atan2(d.X, d.Z)-e.Rotation)/(2*math.Pi)
+	u -= math.Floor(u)
+	v := math.Acos(math.Max(-1, math.Min(1, d.Y))) / math.Pi
+
+	x := int(u * float64(width))
+	y := int(v * float64(height))
+
+	if x >= width {
+		x = width - 1
+	}
+
+	if y >= height {
+		y = height - 1
+	}
+
+	return x, y
+}
+
+// Get a direction towards the map chosen proportionally to the brightness of
+// the pixels, its radiance and the probability density of choosing it with
+// respect to solid angle.
+func (e Environment) sample(rng *rand.Rand) (Vector, canvas.FloatColor, float64) {
+	env := e.Map
+	width, height := env.image.Width(), env.image.Height()
+	total := env.rowCdf[height]
+
+	if total <= 0 {
+		return Vector{}, canvas.FloatColor{}, 0
+	}
+
+	y := searchCdf(env.rowCdf, rng.Float64()*total)
+	row := env.columnCdf[y]
+	x := searchCdf(row, rng.Float64()*row[width])
+
+	// uniform point within the pixel
+	u := (float64(x) + rng.Float64()) / float64(width)
+	v := (float64(y) + rng.Float64()) / float64(height)
+
+	phi := 2*math.Pi*(u-0.5) + e.Rotation
+	sinTheta, cosTheta := math.Sincos(math.Pi * v)
+	sinPhi, cosPhi := math.Sincos(phi)
+	direction := Vector{sinTheta * sinPhi, cosTheta, sinTheta * cosPhi}
+
+	if sinTheta <= 0 {
+		return direction, canvas.FloatColor{}, 0
+	}
+
+	// density of the pixel over the image, converted from the image area to
+	// solid angle
+	rowSinTheta := math.Sin(math.Pi * (float64(y) + 0.5) / float64(height))
+	pixelPdf := env.image.At(x, y).Luminance() * rowSinTheta / total * float64(width*height)
+	pdf := pixelPdf / (2 * math.Pi * math.Pi * sinTheta)
+
+	return direction, env.image.At(x, y).Scale(e.Intensity), pdf
+}
+
+// Find the index i of the interval [cdf[i], cdf[i+1]) containing a value.
+func searchCdf(cdf []float64, value float64) int {
+	i := sort.SearchFloat64s(cdf, value)
+	if i > 0 {
+		i--
+	}
+
+	// skip intervals of zero width
+	for i < len(cdf)-2 && cdf[i+1] <= value {
+		i++
+	}
+
+	return i
+}
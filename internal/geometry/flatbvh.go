@@ -0,0 +1,345 @@
+package geometry
+
+const flatBvhLeaf = 3
+
+// Bounding volume hierarchy stored as an array of nodes in depth-first order.
+// The left child of an inner node directly follows it, the right child is
+// referenced by index. The objects of all leaves are stored contiguously, so
+// each leaf only references a range of them.
+type FlatBvh struct {
+	nodes   []flatBvhNode
+	objects []Object
+	options BvhOptions
+	// bounds of each node at the start and end of the shutter interval, only
+	// if objects move
+	motion []flatBvhMotion
+}
+
+type flatBvhNode struct {
+	// minimum and maximum corner, indexed by the sign bits of a ray
+	bounds [2][3]float64
+	// index of the right child for inner nodes, index of the first object for
+	// leaves
+	offset int32
+	// number of objects in a leaf
+	count int32
+	// split axis of inner nodes, flatBvhLeaf for leaves
+	axis uint8
+}
+
+type flatBvhMotion struct {
+	start [2][3]float64
+	end   [2][3]float64
+}
+
+// Ray with precomputed values for the box tests of a traversal.
+type flatBvhRay struct {
+	origin     [3]float64
+	inverseDir [3]float64
+	sign       [3]uint8
+}
+
+// Convert the tree into its flattened representation.
+func (t BvhTree) Flatten() *FlatBvh {
+	flat := &FlatBvh{options: t.options}
+	flat.appendElement(t.root)
+	flat.calculateMotion()
+
+	return flat
+}
+
+func (f *FlatBvh) appendElement(element bvhTreeElement) int32 {
+	box := element.boundingBox()
+	index := int32(len(f.nodes))
+
+	f.nodes = append(f.nodes, flatBvhNode{
+		bounds: [2][3]float64{
+			{box.minX, box.minY, box.minZ},
+			{box.maxX, box.maxY, box.maxZ},
+		},
+	})
+
+	switch e := element.(type) {
+	case *bvhTreeLeaf:
+		f.nodes[index].offset = int32(len(f.objects))
+		f.nodes[index].count = int32(len(e.objs))
+		f.nodes[index].axis = flatBvhLeaf
+		f.objects = append(f.objects, e.objs...)
+	case *bvhTreeNode:
+		f.nodes[index].axis = uint8(e.axis)
+		f.appendElement(e.left)
+		f.nodes[index].offset = f.appendElement(e.right)
+	}
+
+	return index
+}
+
+// Calculate the bounds of all nodes at the start and end of the shutter
+// interval if any object moves. Objects move linearly, so interpolating these
+// bounds gives boxes containing the objects at any point in time.
+func (f *FlatBvh) calculateMotion() {
+	moving := false
+
+	for _, obj := range f.objects {
+		if m, isMoving := obj.(movingObject); isMoving {
+			start, end := m.motionExtremes()
+			moving = moving || start != end
+		}
+	}
+
+	if !moving {
+		return
+	}
+
+	f.motion = make([]flatBvhMotion, len(f.nodes))
+	f.collectMotion(0)
+}
+
+func (f *FlatBvh) collectMotion(index int32) (extremes, extremes) {
+	node := &f.nodes[index]
+	var start, end extremes
+
+	if node.axis == flatBvhLeaf {
+		for i, obj := range f.objects[node.offset : node.offset+node.count] {
+			objStart, objEnd := obj.extremes(), obj.extremes()
+			if m, isMoving := obj.(movingObject); isMoving {
+				objStart, objEnd = m.motionExtremes()
+			}
+
+			if i == 0 {
+				start, end = objStart, objEnd
+			} else {
+				start, end = merge(start, objStart), merge(end, objEnd)
+			}
+		}
+	} else {
+		leftStart, leftEnd := f.collectMotion(index + 1)
+		rightStart, rightEnd := f.collectMotion(node.offset)
+		start, end = merge(leftStart, rightStart), merge(leftEnd, rightEnd)
+	}
+
+	f.motion[index] = flatBvhMotion{
+		start: [2][3]float64{{start.minX, start.minY, start.minZ}, {start.maxX, start.maxY, start.maxZ}},
+		end:   [2][3]float64{{end.minX, end.minY, end.minZ}, {end.maxX, end.maxY, end.maxZ}},
+	}
+
+	return start, end
+}
+
+// Get the bounds of a node at a point in time of the shutter interval. The
+// bounds are returned by value, so traversals of scenes with motion do not
+// allocate.
+func (m *flatBvhMotion) boundsAt(time float64) [2][3]float64 {
+	var bounds [2][3]float64
+
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 3; j++ {
+			bounds[i][j] = m.start[i][j] + (m.end[i][j]-m.start[i][j])*time
+		}
+	}
+
+	return bounds
+}
+
+// Check whether the ray enters the box of the node at an index before tMax.
+// If objects move, the box at the time of the ray is used.
+func (f *FlatBvh) intersects(index int32, ray *flatBvhRay, time, tMax float64) bool {
+	if f.motion == nil {
+		return intersectsBounds(&f.nodes[index].bounds, ray, tMax)
+	}
+
+	bounds := f.motion[index].boundsAt(time)
+
+	return intersectsBounds(&bounds, ray, tMax)
+}
+
+func newFlatBvhRay(ray Ray) flatBvhRay {
+	flatRay := flatBvhRay{
+		origin:     [3]float64{ray.Origin.X, ray.Origin.Y, ray.Origin.Z},
+		inverseDir: [3]float64{1 / ray.Direction.X, 1 / ray.Direction.Y, 1 / ray.Direction.Z},
+	}
+
+	for i := range flatRay.sign {
+		if flatRay.inverseDir[i] < 0 {
+			flatRay.sign[i] = 1
+		}
+	}
+
+	return flatRay
+}
+
+// Check whether the ray enters the box before tMax. The minimum and maximum
+// corner are indexed by the sign bits of the ray.
+// Source: https://pbr-book.org/3ed-2018/Shapes/Basic_Shape_Interface#RayndashBoundsIntersections
+func intersectsBounds(bounds *[2][3]float64, ray *flatBvhRay, tMax float64) bool {
+	tEnter := (bounds[ray.sign[0]][0] - ray.origin[0]) * ray.inverseDir[0]
+	tExit := (bounds[1-ray.sign[0]][0] - ray.origin[0]) * ray.inverseDir[0]
+	tyEnter := (bounds[ray.sign[1]][1] - ray.origin[1]) * ray.inverseDir[1]
+	tyExit := (bounds[1-ray.sign[1]][1] - ray.origin[1]) * ray.inverseDir[1]
+
+	if tEnter > tyExit || tyEnter > tExit {
+		return false
+	}
+
+	if tyEnter > tEnter {
+		tEnter = tyEnter
+	}
+
+	if tyExit < tExit {
+		tExit = tyExit
+	}
+
+	tzEnter := (bounds[ray.sign[2]][2] - ray.origin[2]) * ray.inverseDir[2]
+	tzExit := (bounds[1-ray.sign[2]][2] - ray.origin[2]) * ray.inverseDir[2]
+
+	if tEnter > tzExit || tzEnter > tExit {
+		return false
+	}
+
+	if tzEnter > tEnter {
+		tEnter = tzEnter
+	}
+
+	if tzExit < tExit {
+		tExit = tzExit
+	}
+
+	return tEnter < tMax && tExit >= 0
+}
+
+// Find the closest object hit by the ray with a ray parameter below tMax.
+// Children are visited front to back and nodes behind the closest hit found so
+// far are skipped.
+func (f *FlatBvh) ClosestHit(ray Ray, tMax float64) (Hit, bool) {
+	var closest Hit
+	found := false
+
+	flatRay := newFlatBvhRay(ray)
+	stack := make([]int32, 0, 64)
+	current := int32(0)
+
+	for {
+		node := &f.nodes[current]
+
+		if f.intersects(current, &flatRay, ray.Time, tMax) {
+			switch {
+			case node.axis == flatBvhLeaf:
+				for _, obj := range f.objects[node.offset : node.offset+node.count] {
+					if hit, intersects := obj.hit(ray, tMax); intersects {
+						hit.Root = obj
+						closest = hit
+						tMax = hit.Distance
+						found = true
+					}
+				}
+			case flatRay.sign[node.axis] == 1:
+				stack = append(stack, current+1)
+				current = node.offset
+				continue
+			default:
+				stack = append(stack, node.offset)
+				current++
+				continue
+			}
+		}
+
+		if len(stack) == 0 {
+			break
+		}
+
+		current = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+	}
+
+	return closest, found
+}
+
+// Check whether the ray hits any object with a ray parameter below maxDistance.
+// Traversal stops at the first hit found, which is not necessarily the closest.
+func (f *FlatBvh) Occluded(ray Ray, maxDistance float64) bool {
+	return f.AnyHit(ray, maxDistance, func(Hit) bool {
+		return true
+	})
+}
+
+// Call accept for hits of the ray with a ray parameter below tMax, in no
+// particular order, until it returns true. Return whether a hit was accepted.
+func (f *FlatBvh) AnyHit(ray Ray, tMax float64, accept func(Hit) bool) bool {
+	flatRay := newFlatBvhRay(ray)
+	stack := make([]int32, 0, 64)
+	current := int32(0)
+
+	for {
+		node := &f.nodes[current]
+
+		if f.intersects(current, &flatRay, ray.Time, tMax) {
+			if node.axis != flatBvhLeaf {
+				stack = append(stack, node.offset)
+				current++
+				continue
+			}
+
+			for _, obj := range f.objects[node.offset : node.offset+node.count] {
+				if obj.anyHit(ray, tMax, accept) {
+					return true
+				}
+			}
+		}
+
+		if len(stack) == 0 {
+			return false
+		}
+
+		current = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+	}
+}
+
+// Get the quality metrics of the hierarchy.
+func (f *FlatBvh) Stats() BvhStats {
+	var stats BvhStats
+	stats.SAHCost = f.collectStats(0, &stats, 0)
+
+	return stats
+}
+
+func (f *FlatBvh) collectStats(index int32, stats *BvhStats, depth int) float64 {
+	node := &f.nodes[index]
+
+	if node.axis == flatBvhLeaf {
+		stats.Leaves++
+		stats.Objects += int(node.count)
+
+		if depth > stats.Depth {
+			stats.Depth = depth
+		}
+
+		if int(node.count) > stats.MaxLeafObjects {
+			stats.MaxLeafObjects = int(node.count)
+		}
+
+		return f.options.IntersectionCost * float64(node.count)
+	}
+
+	stats.Nodes++
+
+	left := &f.nodes[index+1]
+	right := &f.nodes[node.offset]
+	costLeft := f.collectStats(index+1, stats, depth+1)
+	costRight := f.collectStats(node.offset, stats, depth+1)
+
+	area := node.surfaceArea()
+	if area <= 0 {
+		return f.options.TraversalCost + costLeft + costRight
+	}
+
+	return f.options.TraversalCost + (left.surfaceArea()*costLeft+right.surfaceArea()*costRight)/area
+}
+
+func (n *flatBvhNode) surfaceArea() float64 {
+	x := n.bounds[1][0] - n.bounds[0][0]
+	y := n.bounds[1][1] - n.bounds[0][1]
+	z := n.bounds[1][2] - n.bounds[0][2]
+
+	return 2 * (x*y + y*z + z*x)
+}
@@ -0,0 +1,60 @@
+package geometry_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/b-erhart/raytracer/internal/geometry"
+	"github.com/b-erhart/raytracer/internal/wavefront"
+)
+
+const teapotPath = "../../SPEC/objs/teapot.obj"
+
+func BenchmarkBvhTree(b *testing.B) {
+	tree := geometry.ConstructBvhTree(readTeapot(b).Objects(), geometry.DefaultBvhOptions())
+	rays := teapotRays(4096)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, ray := range rays {
+			tree.ClosestHit(ray, 1e9)
+		}
+	}
+}
+
+func BenchmarkFlatBvh(b *testing.B) {
+	flat := geometry.ConstructBvhTree(readTeapot(b).Objects(), geometry.DefaultBvhOptions()).Flatten()
+	rays := teapotRays(4096)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, ray := range rays {
+			flat.ClosestHit(ray, 1e9)
+		}
+	}
+}
+
+// Get the teapot model, read the same way the renderer reads it.
+func readTeapot(tb testing.TB) *geometry.Mesh {
+	mesh, err := wavefront.ReadMesh(teapotPath, geometry.DefaultBvhOptions())
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return mesh
+}
+
+// Get rays from random points around the teapot towards random points within
+// its bounds. The same seed gives every benchmark the same rays.
+func teapotRays(count int) []geometry.Ray {
+	rng := rand.New(rand.NewSource(1))
+	rays := make([]geometry.Ray, count)
+
+	for i := range rays {
+		origin := geometry.Sprod(geometry.Vector{X: rng.NormFloat64(), Y: rng.NormFloat64(), Z: rng.NormFloat64()}.Normalize(), 10)
+		target := geometry.Vector{X: 6*rng.Float64() - 3, Y: 3 * rng.Float64(), Z: 4*rng.Float64() - 2}
+		rays[i] = geometry.Ray{Origin: origin, Direction: geometry.Sub(target, origin).Normalize()}
+	}
+
+	return rays
+}
@@ -0,0 +1,48 @@
+package geometry
+
+import (
+	"math"
+
+	"github.com/b-erhart/raytracer/internal/canvas"
+)
+
+// Exponential fog filling the whole scene, which fades everything seen along
+// a ray towards the fog color with increasing distance. Fog does not cast
+// shadows.
+type Fog struct {
+	Color canvas.Color
+	// Extinction per unit of distance at Height. Zero disables fog.
+	Density float64
+	// Exponential decrease of the density per unit of height above Height.
+	// Zero gives the same density everywhere.
+	HeightFalloff float64
+	Height        float64
+}
+
+// Check whether fog is configured.
+func (f Fog) Enabled() bool {
+	return f.Density > 0
+}
+
+// Get the color seen along a ray segment of the given length, which ends in
+// the given color.
+func (f Fog) apply(ray Ray, distance float64, color canvas.Color) canvas.Color {
+	return color.Merge(f.Color, 1-f.transmittance(ray, distance))
+}
+
+// Get the share of light that passes a ray segment without being scattered.
+func (f Fog) transmittance(ray Ray, distance float64) float64 {
+	density := f.Density
+	if f.HeightFalloff > 0 {
+		density *= math.Exp(-f.HeightFalloff * (ray.Origin.Y - f.Height))
+	}
+
+	// the density along the ray is constant for uniform fog and horizontal
+	// rays, and decreases exponentially otherwise
+	slope := f.HeightFalloff * ray.Direction.Y
+	if math.Abs(slope) < Epsilon {
+		return math.Exp(-density * distance)
+	}
+
+	return math.Exp(-density * (1 - math.Exp(-slope*distance)) / slope)
+}
@@ -0,0 +1,217 @@
+package geometry
+
+import "math"
+
+// Placement of a mesh in the scene. Rays are transformed into the coordinate
+// system of the mesh, so instances of the same mesh share its bounding volume
+// hierarchy.
+type Instance struct {
+	Properties ObjectProps
+	mesh       *Mesh
+	transform  Matrix
+	inverse    Matrix
+	// transformation at the end of the shutter interval
+	endTransform Matrix
+	// nil if the instance does not move
+	motion *instanceMotion
+	extrms extremes
+}
+
+// Motion of an instance, with its start and end transformation split into
+// translation, rotation and scale around the center of the mesh. The center
+// moves on a straight line while the mesh turns and scales around it.
+type instanceMotion struct {
+	center Vector
+	start  decomposedTransform
+	end    decomposedTransform
+}
+
+// Create a new instance of a mesh. The transformation maps mesh coordinates to
+// scene coordinates. The surface properties apply to the whole instance.
+func NewInstance(mesh *Mesh, transform Matrix, props ObjectProps) *Instance {
+	instance := &Instance{
+		Properties: props,
+		mesh:       mesh,
+	}
+	instance.SetTransform(transform)
+
+	return instance
+}
+
+// Create a new instance of a mesh that moves during the shutter interval. The
+// translation, rotation and scale of its transformation are interpolated
+// separately from start to end, rotating the shorter way around.
+func NewMovingInstance(mesh *Mesh, start, end Matrix, props ObjectProps) *Instance {
+	instance := &Instance{
+		Properties: props,
+		mesh:       mesh,
+	}
+	instance.SetMotion(start, end)
+
+	return instance
+}
+
+// Get the mesh that is instanced.
+func (i *Instance) Mesh() *Mesh {
+	return i.mesh
+}
+
+// Get the transformation from mesh coordinates to scene coordinates. For
+// moving instances, this is the transformation at the start of the shutter
+// interval.
+func (i *Instance) Transform() Matrix {
+	return i.transform
+}
+
+// Move the instance by replacing its transformation. The bounding volume
+// hierarchy of the mesh is not affected, but the hierarchy containing the
+// instance has to be rebuilt (see Raytracer.SetObjects).
+func (i *Instance) SetTransform(transform Matrix) {
+	i.SetMotion(transform, transform)
+}
+
+// Move the instance from the start to the end transformation during the
+// shutter interval. Like SetTransform, this requires rebuilding the hierarchy
+// containing the instance. Panics if either transformation is singular or only
+// one of them mirrors the mesh.
+func (i *Instance) SetMotion(start, end Matrix) {
+	i.transform = start
+	i.inverse = start.Inverse()
+	i.endTransform = end
+	i.motion = nil
+
+	if start != end {
+		min, max := i.mesh.Bounds()
+		center := Sprod(Add(min, max), 0.5)
+
+		i.motion = &instanceMotion{
+			center: center,
+			start:  Mul(start, Translation(center)).decompose(),
+			end:    Mul(end, Translation(center)).decompose(),
+		}
+
+		if i.motion.start.mirrored != i.motion.end.mirrored {
+			panic("moving instance can not start or stop mirroring during its motion")
+		}
+	}
+
+	i.calculateExtremes()
+}
+
+func (i *Instance) Props() ObjectProps {
+	return i.Properties
+}
+
+func (i *Instance) extremes() extremes {
+	return i.extrms
+}
+
+// Get extremes at the start and end of the shutter interval, so that
+// interpolating them contains the mesh at any point in time. Without rotation,
+// every point of the mesh moves on a straight line and the bounds of the
+// transformed mesh are used. Otherwise, the bounds of the sphere around the
+// mesh are used, whose center moves on a straight line.
+func (i *Instance) motionExtremes() (extremes, extremes) {
+	if i.motion == nil || math.Abs(i.motion.start.rotation.dot(i.motion.end.rotation)) > 1-1e-12 {
+		return i.transformedExtremes(i.transform), i.transformedExtremes(i.endTransform)
+	}
+
+	min, max := i.mesh.Bounds()
+	radius := Sub(max, min).Length() / 2
+
+	// the Frobenius norm of the scale is at least the factor by which it
+	// stretches any vector
+	return sphereExtremes(i.motion.start.translation, radius*frobeniusNorm(i.motion.start.scale)),
+		sphereExtremes(i.motion.end.translation, radius*frobeniusNorm(i.motion.end.scale))
+}
+
+func (i *Instance) hit(ray Ray, tMax float64) (Hit, bool) {
+	inverse := i.inverseAt(ray.Time)
+
+	hit, intersects := i.mesh.hit(localRay(ray, inverse), tMax)
+	if !intersects {
+		return Hit{}, false
+	}
+
+	return i.sceneHit(hit, inverse), true
+}
+
+func (i *Instance) anyHit(ray Ray, tMax float64, accept func(Hit) bool) bool {
+	inverse := i.inverseAt(ray.Time)
+
+	return i.mesh.anyHit(localRay(ray, inverse), tMax, func(hit Hit) bool {
+		return accept(i.sceneHit(hit, inverse))
+	})
+}
+
+// Get the transformation from scene coordinates to mesh coordinates at a point
+// in time of the shutter interval.
+func (i *Instance) inverseAt(time float64) Matrix {
+	if i.motion == nil {
+		return i.inverse
+	}
+
+	transform := interpolateTransform(i.motion.start, i.motion.end, time)
+
+	return Mul(transform, Translation(Sprod(i.motion.center, -1))).Inverse()
+}
+
+// Transform a ray into the coordinate system of the mesh. The direction is not
+// normalized, so ray parameters are the same in both coordinate systems.
+func localRay(ray Ray, inverse Matrix) Ray {
+	return Ray{
+		Origin:    inverse.Point(ray.Origin),
+		Direction: inverse.Direction(ray.Direction),
+		Depth:     ray.Depth,
+		Time:      ray.Time,
+	}
+}
+
+// Transform a hit on the mesh into scene coordinates.
+func (i *Instance) sceneHit(hit Hit, inverse Matrix) Hit {
+	hit.Normal = inverse.transposedDirection(hit.Normal).Normalize()
+	hit.Props = i.Properties
+
+	return hit
+}
+
+func (i *Instance) calculateExtremes() {
+	start, end := i.motionExtremes()
+	i.extrms = merge(start, end)
+}
+
+// Get the extremes of the mesh bounds under a transformation.
+func (i *Instance) transformedExtremes(transform Matrix) extremes {
+	min, max := i.mesh.Bounds()
+
+	extrms := extremes{
+		minX: math.Inf(1),
+		minY: math.Inf(1),
+		minZ: math.Inf(1),
+		maxX: math.Inf(-1),
+		maxY: math.Inf(-1),
+		maxZ: math.Inf(-1),
+	}
+
+	for _, x := range []float64{min.X, max.X} {
+		for _, y := range []float64{min.Y, max.Y} {
+			for _, z := range []float64{min.Z, max.Z} {
+				corner := transform.Point(Vector{x, y, z})
+				extrms = merge(extrms, extremes{corner.X, corner.Y, corner.Z, corner.X, corner.Y, corner.Z})
+			}
+		}
+	}
+
+	return extrms
+}
+
+func frobeniusNorm(m Matrix) float64 {
+	sum := 0.0
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			sum += m[i][j] * m[i][j]
+		}
+	}
+
+	return math.Sqrt(sum)
+}
@@ -0,0 +1,71 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+
+	"github.com/b-erhart/raytracer/internal/canvas"
+)
+
+func TestMovingInstanceRotatesHalfTurn(t *testing.T) {
+	// a horizontal 4x1 plank turning half a turn around the vertical axis
+	props := ObjectProps{Color: canvas.Color{R: 200, G: 200, B: 200}}
+	instance := NewMovingInstance(plankMesh(), Identity(), RotationY(math.Pi), props)
+
+	hits := func(x, z, time float64) bool {
+		hit, found := instance.hit(Ray{Origin: Vector{x, 10, z}, Direction: Vector{0, -1, 0}, Time: time}, math.Inf(1))
+		if found && math.Abs(hit.Distance-10) > 1e-9 {
+			t.Fatalf("ray at (%g, %g) and time %g hits at distance %g, want 10", x, z, time, hit.Distance)
+		}
+
+		return found
+	}
+
+	// the turn may go either way, but the plank must keep its shape and turn
+	// by a quarter of a turn in each half of the shutter interval
+	for _, time := range []float64{0, 0.25, 0.5, 0.75, 1} {
+		angle := math.Pi * time
+		along := hits(1.5*math.Cos(angle), 1.5*math.Sin(angle), time)
+		mirrored := hits(1.5*math.Cos(angle), -1.5*math.Sin(angle), time)
+
+		if !hits(0, 0, time) || (!along && !mirrored) {
+			t.Errorf("time %g: plank is not turned by %g degrees", time, 180*time)
+		}
+
+		if (time == 0.25 || time == 0.75) && along && mirrored {
+			t.Errorf("time %g: plank is distorted", time)
+		}
+
+		if time == 0.5 && hits(1.5, 0, time) {
+			t.Errorf("time %g: plank is not turned by 90 degrees", time)
+		}
+	}
+
+	raytracer := NewRaytracer(Scene{
+		Objects:       []Object{instance},
+		Lights:        []Light{{Direction: Vector{0, -1, 0}, Color: canvas.Color{R: 255, G: 255, B: 255}}},
+		BvhOptions:    DefaultBvhOptions(),
+		RenderOptions: DefaultRenderOptions(),
+		Antialiasing:  DefaultAntialiasingOptions(),
+	})
+
+	canv := canvas.NewCanvas(32, 32)
+	raytracer.Render(NewView(32, 32, Vector{0, 10, 0}, Vector{0, -1, 0}, Vector{0, 0, 1}, 45), canv)
+
+	image := canv.Image()
+	if center := image.RGBAAt(16, 16); center.R == 0 {
+		t.Errorf("center pixel is %v, want the lit plank", center)
+	}
+
+	if corner := image.RGBAAt(0, 0); corner.R != 0 {
+		t.Errorf("corner pixel is %v, want the background outside the turning plank", corner)
+	}
+}
+
+// Get a mesh of a flat plank, 4 units along x and 1 unit along z.
+func plankMesh() *Mesh {
+	return NewMesh([]Object{
+		&Triangle{A: Vector{-2, 0, -0.5}, B: Vector{2, 0, -0.5}, C: Vector{2, 0, 0.5}},
+		&Triangle{A: Vector{-2, 0, -0.5}, B: Vector{2, 0, 0.5}, C: Vector{-2, 0, 0.5}},
+	}, DefaultBvhOptions())
+}
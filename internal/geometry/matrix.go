@@ -0,0 +1,245 @@
+package geometry
+
+import "math"
+
+// Affine transformation matrix. The omitted last row is always (0, 0, 0, 1).
+type Matrix [3][4]float64
+
+// Get the identity transformation.
+func Identity() Matrix {
+	return Matrix{
+		{1, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, 1, 0},
+	}
+}
+
+// Get a transformation that moves points by the given vector.
+func Translation(v Vector) Matrix {
+	return Matrix{
+		{1, 0, 0, v.X},
+		{0, 1, 0, v.Y},
+		{0, 0, 1, v.Z},
+	}
+}
+
+// Get a transformation that scales uniformly by the given factor.
+func Scaling(factor float64) Matrix {
+	return Matrix{
+		{factor, 0, 0, 0},
+		{0, factor, 0, 0},
+		{0, 0, factor, 0},
+	}
+}
+
+// Get a transformation that rotates around the x axis. The angle is given in
+// radians.
+func RotationX(angle float64) Matrix {
+	sin, cos := math.Sincos(angle)
+
+	return Matrix{
+		{1, 0, 0, 0},
+		{0, cos, -sin, 0},
+		{0, sin, cos, 0},
+	}
+}
+
+// Get a transformation that rotates around the y axis. The angle is given in
+// radians.
+func RotationY(angle float64) Matrix {
+	sin, cos := math.Sincos(angle)
+
+	return Matrix{
+		{cos, 0, sin, 0},
+		{0, 1, 0, 0},
+		{-sin, 0, cos, 0},
+	}
+}
+
+// Get a transformation that rotates around the z axis. The angle is given in
+// radians.
+func RotationZ(angle float64) Matrix {
+	sin, cos := math.Sincos(angle)
+
+	return Matrix{
+		{cos, -sin, 0, 0},
+		{sin, cos, 0, 0},
+		{0, 0, 1, 0},
+	}
+}
+
+// Get a transformation that rotates around an axis through the origin. The
+// angle is given in radians.
+// Source: https://en.wikipedia.org/wiki/Rotation_matrix#Rotation_matrix_from_axis_and_angle
+func Rotation(axis Vector, angle float64) Matrix {
+	a := axis.Normalize()
+	sin, cos := math.Sincos(angle)
+	c := 1 - cos
+
+	return Matrix{
+		{cos + a.X*a.X*c, a.X*a.Y*c - a.Z*sin, a.X*a.Z*c + a.Y*sin, 0},
+		{a.Y*a.X*c + a.Z*sin, cos + a.Y*a.Y*c, a.Y*a.Z*c - a.X*sin, 0},
+		{a.Z*a.X*c - a.Y*sin, a.Z*a.Y*c + a.X*sin, cos + a.Z*a.Z*c, 0},
+	}
+}
+
+// Get the product of two transformations. The result applies b first and a
+// second.
+func Mul(a, b Matrix) Matrix {
+	var m Matrix
+
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 4; j++ {
+			m[i][j] = a[i][0]*b[0][j] + a[i][1]*b[1][j] + a[i][2]*b[2][j]
+		}
+		m[i][3] += a[i][3]
+	}
+
+	return m
+}
+
+// Transformation split into a translation, a rotation and a scale, which also
+// holds any shear. Applied to a point, the scale comes first and the
+// translation last. Unlike the elements of the matrix, the parts can be
+// interpolated without distorting the transformed objects.
+type decomposedTransform struct {
+	translation Vector
+	rotation    quaternion
+	scale       Matrix
+	// whether the transformation mirrors, which the rotation can not express
+	mirrored bool
+}
+
+// Split the transformation into translation, rotation and scale. The rotation
+// is found by polar decomposition, averaging the matrix with its inverse
+// transpose until it converges. Panics if the transformation is singular.
+// Source: https://pbr-book.org/3ed-2018/Geometry_and_Transformations/Animating_Transformations
+func (m Matrix) decompose() decomposedTransform {
+	linear := m
+	for i := 0; i < 3; i++ {
+		linear[i][3] = 0
+	}
+
+	rotation := linear
+	for iteration := 0; iteration < 100; iteration++ {
+		inverse := rotation.Inverse()
+		change := 0.0
+
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				next := (rotation[i][j] + inverse[j][i]) / 2
+				change = math.Max(change, math.Abs(next-rotation[i][j]))
+				rotation[i][j] = next
+			}
+		}
+
+		if change < 1e-12 {
+			break
+		}
+	}
+
+	// a mirroring rotation is turned into a proper one by negating it and the
+	// scale
+	mirrored := rotation.determinant() < 0
+	if mirrored {
+		rotation = Mul(Scaling(-1), rotation)
+	}
+
+	return decomposedTransform{
+		translation: Vector{m[0][3], m[1][3], m[2][3]},
+		rotation:    rotationQuaternion(rotation),
+		scale:       Mul(rotation.transposed(), linear),
+		mirrored:    mirrored,
+	}
+}
+
+// Interpolate between two decomposed transformations, with t from 0 (a) to 1
+// (b). Translation and scale are interpolated linearly, the rotation turns at
+// constant speed.
+func interpolateTransform(a, b decomposedTransform, t float64) Matrix {
+	var scale Matrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			scale[i][j] = a.scale[i][j] + (b.scale[i][j]-a.scale[i][j])*t
+		}
+	}
+
+	m := Mul(slerp(a.rotation, b.rotation, t).matrix(), scale)
+	translation := Add(a.translation, Sprod(Sub(b.translation, a.translation), t))
+	m[0][3], m[1][3], m[2][3] = translation.X, translation.Y, translation.Z
+
+	return m
+}
+
+// Apply the transformation to a point.
+func (m Matrix) Point(p Vector) Vector {
+	return Vector{
+		m[0][0]*p.X + m[0][1]*p.Y + m[0][2]*p.Z + m[0][3],
+		m[1][0]*p.X + m[1][1]*p.Y + m[1][2]*p.Z + m[1][3],
+		m[2][0]*p.X + m[2][1]*p.Y + m[2][2]*p.Z + m[2][3],
+	}
+}
+
+// Apply the transformation to a direction. Translation is ignored.
+func (m Matrix) Direction(d Vector) Vector {
+	return Vector{
+		m[0][0]*d.X + m[0][1]*d.Y + m[0][2]*d.Z,
+		m[1][0]*d.X + m[1][1]*d.Y + m[1][2]*d.Z,
+		m[2][0]*d.X + m[2][1]*d.Y + m[2][2]*d.Z,
+	}
+}
+
+// Apply the transposed linear part of the transformation to a vector. Called
+// on an inverse transformation, this maps surface normals.
+func (m Matrix) transposedDirection(d Vector) Vector {
+	return Vector{
+		m[0][0]*d.X + m[1][0]*d.Y + m[2][0]*d.Z,
+		m[0][1]*d.X + m[1][1]*d.Y + m[2][1]*d.Z,
+		m[0][2]*d.X + m[1][2]*d.Y + m[2][2]*d.Z,
+	}
+}
+
+// Get the inverse transformation. Panics if the transformation is singular.
+func (m Matrix) Inverse() Matrix {
+	det := m.determinant()
+
+	if math.Abs(det) < Epsilon*Epsilon {
+		panic("cannot invert singular transformation matrix")
+	}
+
+	f := 1 / det
+
+	var inv Matrix
+	inv[0][0] = f * (m[1][1]*m[2][2] - m[1][2]*m[2][1])
+	inv[0][1] = f * (m[0][2]*m[2][1] - m[0][1]*m[2][2])
+	inv[0][2] = f * (m[0][1]*m[1][2] - m[0][2]*m[1][1])
+	inv[1][0] = f * (m[1][2]*m[2][0] - m[1][0]*m[2][2])
+	inv[1][1] = f * (m[0][0]*m[2][2] - m[0][2]*m[2][0])
+	inv[1][2] = f * (m[0][2]*m[1][0] - m[0][0]*m[1][2])
+	inv[2][0] = f * (m[1][0]*m[2][1] - m[1][1]*m[2][0])
+	inv[2][1] = f * (m[0][1]*m[2][0] - m[0][0]*m[2][1])
+	inv[2][2] = f * (m[0][0]*m[1][1] - m[0][1]*m[1][0])
+
+	t := inv.Direction(Vector{m[0][3], m[1][3], m[2][3]})
+	inv[0][3] = -t.X
+	inv[1][3] = -t.Y
+	inv[2][3] = -t.Z
+
+	return inv
+}
+
+// Get the determinant of the linear part.
+func (m Matrix) determinant() float64 {
+	return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+}
+
+// Get the transposed linear part. Translation is dropped.
+func (m Matrix) transposed() Matrix {
+	return Matrix{
+		{m[0][0], m[1][0], m[2][0], 0},
+		{m[0][1], m[1][1], m[2][1], 0},
+		{m[0][2], m[1][2], m[2][2], 0},
+	}
+}
@@ -0,0 +1,89 @@
+package geometry
+
+import (
+	"math"
+
+	"github.com/b-erhart/raytracer/internal/canvas"
+)
+
+// Homogeneous participating medium filling a closed object, e.g. smoke or
+// murky water. The surface of the object is invisible, only the medium inside
+// absorbs light and scatters light of the light sources towards the viewer.
+type Medium struct {
+	// Absorbed share of light per unit of distance.
+	Absorption float64
+	// Scattered share of light per unit of distance.
+	Scattering float64
+	// Color of the scattered light.
+	Color canvas.Color
+	// Asymmetry of the Henyey-Greenstein phase function, from -1 (back
+	// scattering) over 0 (isotropic) to 1 (forward scattering).
+	Asymmetry float64
+	// Number of points along a ray at which scattered light is gathered.
+	Samples int
+}
+
+// Get the share of light that is absorbed or scattered per unit of distance.
+func (m *Medium) extinction() float64 {
+	return m.Absorption + m.Scattering
+}
+
+// Get the probability density of light being scattered by the angle whose
+// cosine is given, per unit of solid angle. It integrates to 1 over the
+// sphere.
+// Source: https://pbr-book.org/3ed-2018/Volume_Scattering/Phase_Functions#TheHenyeyndashGreensteinPhaseFunction
+func (m *Medium) phase(cos float64) float64 {
+	g := m.Asymmetry
+	denominator := 1 + g*g - 2*g*cos
+
+	return (1 - g*g) / (4 * math.Pi * denominator * math.Sqrt(denominator))
+}
+
+// Get the color seen along a ray segment of the given length through the
+// medium of the ray, which ends in the given color. Light scattered towards
+// the ray is gathered at jittered points along the segment.
+func (r *Raytracer) throughMedium(w *worker, ray Ray, distance float64, color canvas.Color) canvas.Color {
+	medium := ray.Medium
+	extinction := medium.extinction()
+	if extinction <= 0 {
+		return color
+	}
+
+	transmittance := math.Exp(-extinction * distance)
+
+	// beyond this length, less than 0.1% of the scattered light arrives
+	length := math.Min(distance, math.Log(1000)/extinction)
+
+	samples := medium.Samples
+	if samples < 1 {
+		samples = 1
+	}
+
+	step := length / float64(samples)
+	var scattered canvas.FloatColor
+
+	for i := 0; i < samples; i++ {
+		t := (float64(i) + w.rng.Float64()) * step
+		point := ray.At(t)
+		weight := medium.Scattering * math.Exp(-extinction*t) * step
+
+		for _, light := range r.lights {
+			towardsLight := Sprod(light.Direction, -1).Normalize()
+			rayToLight := Ray{
+				Origin:    point,
+				Direction: towardsLight,
+				Time:      ray.Time,
+			}
+
+			lightColor, visible := r.incomingLight(w, rayToLight, math.Inf(1), light.Color)
+			if visible {
+				// the light is turned from its own direction into the
+				// opposite direction of the ray
+				phase := medium.phase(Dot(towardsLight, ray.Direction))
+				scattered = scattered.Add(lightColor.Float().Scale(weight * phase))
+			}
+		}
+	}
+
+	return color.Float().Scale(transmittance).Add(scattered.Mul(medium.Color.Float())).Color()
+}
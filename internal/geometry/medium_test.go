@@ -0,0 +1,26 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPhaseIntegratesToOne(t *testing.T) {
+	const steps = 100000
+
+	for _, g := range []float64{-0.9, -0.5, 0, 0.3, 0.8} {
+		medium := &Medium{Asymmetry: g}
+
+		// the phase function only depends on the polar angle, so the integral
+		// over the sphere is 2π times the integral over its cosine
+		sum := 0.0
+		for i := 0; i < steps; i++ {
+			cos := -1 + 2*(float64(i)+0.5)/steps
+			sum += medium.phase(cos) * 2 / steps
+		}
+
+		if integral := 2 * math.Pi * sum; math.Abs(integral-1) > 1e-3 {
+			t.Errorf("phase function with asymmetry %v integrates to %v, want 1", g, integral)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package geometry
+
+// Collection of objects with its own (bottom-level) bounding volume hierarchy.
+// Meshes are placed in a scene through instances, so one mesh can be shared by
+// several instances and its hierarchy is only built once.
+type Mesh struct {
+	objects []Object
+	bvh     *FlatBvh
+	extrms  extremes
+}
+
+// Create a new mesh from the given objects and build its bounding volume
+// hierarchy.
+func NewMesh(objects []Object, bvhOptions BvhOptions) *Mesh {
+	if len(objects) == 0 {
+		panic("mesh must contain at least one object")
+	}
+
+	for _, obj := range objects {
+		if triangle, isTriangle := obj.(*Triangle); isTriangle && !triangle.edgesCalculated {
+			triangle.calculateEdges()
+		}
+	}
+
+	return &Mesh{
+		objects: objects,
+		bvh:     ConstructBvhTree(objects, bvhOptions).Flatten(),
+		extrms:  extremes(calculateBoundingBox(objects)),
+	}
+}
+
+// Get the objects of the mesh.
+func (m *Mesh) Objects() []Object {
+	return m.objects
+}
+
+// Get the corners of the axis-aligned bounding box of the mesh.
+func (m *Mesh) Bounds() (min, max Vector) {
+	return Vector{m.extrms.minX, m.extrms.minY, m.extrms.minZ}, Vector{m.extrms.maxX, m.extrms.maxY, m.extrms.maxZ}
+}
+
+// Get the quality metrics of the bounding volume hierarchy of the mesh.
+func (m *Mesh) BvhStats() BvhStats {
+	return m.bvh.Stats()
+}
+
+func (m *Mesh) hit(ray Ray, tMax float64) (Hit, bool) {
+	return m.bvh.ClosestHit(ray, tMax)
+}
+
+func (m *Mesh) anyHit(ray Ray, tMax float64, accept func(Hit) bool) bool {
+	return m.bvh.AnyHit(ray, tMax, accept)
+}
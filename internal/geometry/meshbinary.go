@@ -0,0 +1,229 @@
+package geometry
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Version of the binary mesh format. Increase it whenever the layout of the
+// format or the construction of the hierarchy changes.
+const MeshBinaryVersion = 1
+
+// Largest number of triangles of a binary mesh. Counts in the header are
+// checked against it before anything is allocated, so a corrupt file cannot
+// request huge amounts of memory.
+const maxMeshBinaryTriangles = 1 << 25
+
+var meshBinaryMagic = [4]byte{'R', 'T', 'M', 'B'}
+
+type meshBinaryHeader struct {
+	Magic            [4]byte
+	Version          uint32
+	Split            int32
+	MaxLeafSize      int32
+	Bins             int32
+	TraversalCost    float64
+	IntersectionCost float64
+	Triangles        uint32
+	Nodes            uint32
+}
+
+type meshBinaryTriangle struct {
+	Corners    [3][3]float64
+	Normals    [3][3]float64
+	NormalsSet uint8
+}
+
+type meshBinaryNode struct {
+	Bounds [2][3]float64
+	Offset int32
+	Count  int32
+	Axis   uint8
+}
+
+// Write the mesh including its bounding volume hierarchy in a compact binary
+// format, so it can be loaded without rebuilding the hierarchy. Only meshes
+// consisting of triangles are supported.
+func (m *Mesh) WriteBinary(w io.Writer) error {
+	options := m.bvh.options
+
+	header := meshBinaryHeader{
+		Magic:            meshBinaryMagic,
+		Version:          MeshBinaryVersion,
+		Split:            int32(options.Split),
+		MaxLeafSize:      int32(options.MaxLeafSize),
+		Bins:             int32(options.Bins),
+		TraversalCost:    options.TraversalCost,
+		IntersectionCost: options.IntersectionCost,
+		Triangles:        uint32(len(m.bvh.objects)),
+		Nodes:            uint32(len(m.bvh.nodes)),
+	}
+
+	triangles := make([]meshBinaryTriangle, len(m.bvh.objects))
+	for i, obj := range m.bvh.objects {
+		t, isTriangle := obj.(*Triangle)
+		if !isTriangle {
+			return fmt.Errorf("mesh object #%d is not a triangle", i)
+		}
+
+		triangles[i] = meshBinaryTriangle{
+			Corners: [3][3]float64{vectorArray(t.A), vectorArray(t.B), vectorArray(t.C)},
+			Normals: [3][3]float64{vectorArray(t.ASurfaceNormal), vectorArray(t.BSurfaceNormal), vectorArray(t.CSurfaceNormal)},
+		}
+
+		if t.NormalsSet {
+			triangles[i].NormalsSet = 1
+		}
+	}
+
+	nodes := make([]meshBinaryNode, len(m.bvh.nodes))
+	for i, node := range m.bvh.nodes {
+		nodes[i] = meshBinaryNode{
+			Bounds: node.bounds,
+			Offset: node.offset,
+			Count:  node.count,
+			Axis:   node.axis,
+		}
+	}
+
+	writer := bufio.NewWriter(w)
+
+	for _, data := range []interface{}{header, triangles, nodes} {
+		if err := binary.Write(writer, binary.LittleEndian, data); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+// Read a mesh written by Mesh.WriteBinary. If r is seekable, the counts in the
+// header are also checked against the remaining size of r.
+func ReadMeshBinary(r io.Reader) (*Mesh, error) {
+	remaining, err := remainingSize(r)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(r)
+
+	var header meshBinaryHeader
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	if header.Magic != meshBinaryMagic {
+		return nil, errors.New("not a binary mesh file")
+	} else if header.Version != MeshBinaryVersion {
+		return nil, fmt.Errorf("unsupported binary mesh version %d (expected %d)", header.Version, MeshBinaryVersion)
+	} else if header.Triangles == 0 || header.Nodes == 0 {
+		return nil, errors.New("binary mesh is empty")
+	} else if header.Triangles > maxMeshBinaryTriangles || header.Nodes >= 2*header.Triangles {
+		return nil, fmt.Errorf("binary mesh has too many triangles (%d) or nodes (%d)", header.Triangles, header.Nodes)
+	}
+
+	size := int64(binary.Size(header)) +
+		int64(header.Triangles)*int64(binary.Size(meshBinaryTriangle{})) +
+		int64(header.Nodes)*int64(binary.Size(meshBinaryNode{}))
+	if remaining >= 0 && size != remaining {
+		return nil, fmt.Errorf("binary mesh has %d bytes (expected %d)", remaining, size)
+	}
+
+	triangles := make([]meshBinaryTriangle, header.Triangles)
+	if err := binary.Read(reader, binary.LittleEndian, triangles); err != nil {
+		return nil, fmt.Errorf("failed to read triangles: %w", err)
+	}
+
+	nodes := make([]meshBinaryNode, header.Nodes)
+	if err := binary.Read(reader, binary.LittleEndian, nodes); err != nil {
+		return nil, fmt.Errorf("failed to read hierarchy: %w", err)
+	}
+
+	bvh := &FlatBvh{
+		nodes:   make([]flatBvhNode, len(nodes)),
+		objects: make([]Object, len(triangles)),
+		options: BvhOptions{
+			Split:            BvhSplit(header.Split),
+			MaxLeafSize:      int(header.MaxLeafSize),
+			Bins:             int(header.Bins),
+			TraversalCost:    header.TraversalCost,
+			IntersectionCost: header.IntersectionCost,
+		},
+	}
+
+	for i, t := range triangles {
+		triangle := &Triangle{
+			A:              arrayVector(t.Corners[0]),
+			B:              arrayVector(t.Corners[1]),
+			C:              arrayVector(t.Corners[2]),
+			ASurfaceNormal: arrayVector(t.Normals[0]),
+			BSurfaceNormal: arrayVector(t.Normals[1]),
+			CSurfaceNormal: arrayVector(t.Normals[2]),
+			NormalsSet:     t.NormalsSet != 0,
+		}
+		triangle.calculateEdges()
+		triangle.calculateExtremes()
+
+		bvh.objects[i] = triangle
+	}
+
+	for i, node := range nodes {
+		if node.Axis == flatBvhLeaf {
+			if node.Offset < 0 || node.Count < 0 || int(node.Offset)+int(node.Count) > len(triangles) {
+				return nil, fmt.Errorf("leaf #%d references objects out of range", i)
+			}
+		} else if node.Axis > 2 || int(node.Offset) <= i || int(node.Offset) >= len(nodes) || i+1 >= len(nodes) {
+			return nil, fmt.Errorf("node #%d references children out of range", i)
+		}
+
+		bvh.nodes[i] = flatBvhNode{
+			bounds: node.Bounds,
+			offset: node.Offset,
+			count:  node.Count,
+			axis:   node.Axis,
+		}
+	}
+
+	root := bvh.nodes[0].bounds
+
+	return &Mesh{
+		objects: bvh.objects,
+		bvh:     bvh,
+		extrms:  extremes{root[0][0], root[0][1], root[0][2], root[1][0], root[1][1], root[1][2]},
+	}, nil
+}
+
+// Get the number of bytes left in r, or -1 if r is not seekable.
+func remainingSize(r io.Reader) (int64, error) {
+	seeker, isSeeker := r.(io.Seeker)
+	if !isSeeker {
+		return -1, nil
+	}
+
+	current, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get position: %w", err)
+	}
+
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get size: %w", err)
+	}
+
+	if _, err := seeker.Seek(current, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to restore position: %w", err)
+	}
+
+	return end - current, nil
+}
+
+func vectorArray(v Vector) [3]float64 {
+	return [3]float64{v.X, v.Y, v.Z}
+}
+
+func arrayVector(a [3]float64) Vector {
+	return Vector{a[0], a[1], a[2]}
+}
@@ -0,0 +1,104 @@
+package geometry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMeshBinaryRoundTrip(t *testing.T) {
+	mesh := binaryTestMesh()
+	data := writeMeshBinary(t, mesh)
+
+	// bytes.Reader is seekable, the struct wrapping it is not
+	for _, r := range []io.Reader{bytes.NewReader(data), struct{ io.Reader }{bytes.NewReader(data)}} {
+		read, err := ReadMeshBinary(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(read.Objects()) != len(mesh.Objects()) || len(read.bvh.nodes) != len(mesh.bvh.nodes) {
+			t.Fatalf("read %d objects and %d nodes, want %d and %d", len(read.Objects()), len(read.bvh.nodes), len(mesh.Objects()), len(mesh.bvh.nodes))
+		}
+
+		for x := -4.5; x < 4.5; x += 0.25 {
+			ray := Ray{Origin: Vector{x, 0.3, -5}, Direction: Vector{0, 0, 1}}
+
+			want, wantFound := mesh.hit(ray, math.Inf(1))
+			got, found := read.hit(ray, math.Inf(1))
+			if found != wantFound || got.Distance != want.Distance {
+				t.Fatalf("ray at x=%g: hit %v at %g, want %v at %g", x, found, got.Distance, wantFound, want.Distance)
+			}
+		}
+	}
+}
+
+func TestMeshBinaryRejectsCorruptData(t *testing.T) {
+	data := writeMeshBinary(t, binaryTestMesh())
+
+	headerSize := binary.Size(meshBinaryHeader{})
+	nodesStart := headerSize + len(binaryTestMesh().Objects())*binary.Size(meshBinaryTriangle{})
+
+	tests := []struct {
+		name   string
+		modify func(data []byte) []byte
+		err    string
+	}{
+		{"version", func(data []byte) []byte {
+			binary.LittleEndian.PutUint32(data[4:], MeshBinaryVersion+1)
+			return data
+		}, "unsupported binary mesh version"},
+		{"triangle count", func(data []byte) []byte {
+			binary.LittleEndian.PutUint32(data[headerSize-8:], 1<<31)
+			return data
+		}, "too many triangles"},
+		{"node count", func(data []byte) []byte {
+			binary.LittleEndian.PutUint32(data[headerSize-4:], 1<<20)
+			return data
+		}, "too many triangles"},
+		{"truncated", func(data []byte) []byte {
+			return data[:len(data)-1]
+		}, "expected"},
+		{"node offset", func(data []byte) []byte {
+			binary.LittleEndian.PutUint32(data[nodesStart+48:], 1<<30)
+			return data
+		}, "out of range"},
+	}
+
+	for _, test := range tests {
+		corrupt := test.modify(append([]byte(nil), data...))
+
+		_, err := ReadMeshBinary(bytes.NewReader(corrupt))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+// Get a mesh of a row of quads, large enough to have inner nodes.
+func binaryTestMesh() *Mesh {
+	var objects []Object
+	for x := -4.0; x < 4; x++ {
+		objects = append(objects,
+			&Triangle{A: Vector{x, 0, x}, B: Vector{x + 1, 0, x}, C: Vector{x + 1, 1, x}},
+			&Triangle{A: Vector{x, 0, x}, B: Vector{x + 1, 1, x}, C: Vector{x, 1, x}},
+		)
+	}
+
+	options := DefaultBvhOptions()
+	options.MaxLeafSize = 2
+
+	return NewMesh(objects, options)
+}
+
+func writeMeshBinary(t *testing.T, mesh *Mesh) []byte {
+	var buf bytes.Buffer
+	if err := mesh.WriteBinary(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
@@ -3,10 +3,18 @@ package geometry
 import "github.com/b-erhart/raytracer/internal/canvas"
 
 type Object interface {
-	Intersection(ray Ray) (bool, float64)
-	SurfaceNormal(point Vector) Vector
 	Props() ObjectProps
 	extremes() extremes
+	hit(ray Ray, tMax float64) (Hit, bool)
+	anyHit(ray Ray, tMax float64, accept func(Hit) bool) bool
+}
+
+// Object that moves during the shutter interval. Its extremes cover its whole
+// motion.
+type movingObject interface {
+	Object
+	// Get the extremes at the start and at the end of the shutter interval.
+	motionExtremes() (extremes, extremes)
 }
 
 type ObjectProps struct {
@@ -14,4 +22,26 @@ type ObjectProps struct {
 	Reflectivity float64
 	Mirror       float64
 	Specular     float64
+	Transparency float64
+	// Medium filling the object, which makes its surface invisible.
+	Medium *Medium
+	// Light transport below the surface of a translucent object.
+	Subsurface *Subsurface
+}
+
+// Intersection of a ray with an object.
+type Hit struct {
+	// Primitive (sphere, triangle, ...) that was hit. For instances, this is
+	// the primitive of the instanced mesh.
+	Object Object
+	// Object of the scene that was hit, i.e. the instance for primitives of
+	// instanced meshes. Only set by the closest hit of a hierarchy.
+	Root Object
+	// Ray parameter of the intersection. Equals the distance to the ray origin
+	// for normalized ray directions.
+	Distance float64
+	// Normalized surface normal at the intersection.
+	Normal Vector
+	// Surface properties at the intersection.
+	Props ObjectProps
 }
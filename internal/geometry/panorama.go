@@ -0,0 +1,92 @@
+package geometry
+
+import "math"
+
+// Get the direction of the ray through the point (x, y) of the canvas for a
+// panoramic projection, and whether the projection covers the point.
+func (v View) panoramaDirection(x, y float64) (Vector, bool) {
+	forward := v.lookAt.Normalize()
+	right := v.u
+	// the v axis points downwards on the canvas
+	down := v.v
+
+	// position relative to the canvas size, with pixel centers at half steps
+	nx := (x + 0.5) / float64(v.width)
+	ny := (y + 0.5) / float64(v.height)
+
+	switch v.projection {
+	case ProjectionEquirectangular:
+		sinLon, cosLon := math.Sincos((nx - 0.5) * 2 * math.Pi)
+		sinLat, cosLat := math.Sincos((0.5 - ny) * math.Pi)
+
+		horizontal := Add(Sprod(right, sinLon), Sprod(forward, cosLon))
+
+		return Add(Sprod(horizontal, cosLat), Sprod(down, -sinLat)).Normalize(), true
+	case ProjectionFisheye:
+		return fisheyeDirection(x, y, v.width, v.height, v.fov, forward, right, down)
+	default:
+		return cubemapDirection(nx, ny, forward, right, down), true
+	}
+}
+
+// Map a point to a direction whose angle to the forward direction grows
+// linearly with its distance from the image center. The image circle fits the
+// shorter side of the canvas and spans fov degrees.
+func fisheyeDirection(x, y float64, width, height int, fov float64, forward, right, down Vector) (Vector, bool) {
+	radius := float64(width) / 2
+	if height < width {
+		radius = float64(height) / 2
+	}
+
+	px := (x + 0.5 - float64(width)/2) / radius
+	py := (y + 0.5 - float64(height)/2) / radius
+	distance := math.Hypot(px, py)
+
+	if distance > 1 {
+		return Vector{}, false
+	} else if distance == 0 {
+		return forward, true
+	}
+
+	sin, cos := math.Sincos(distance * fov / 2 * (math.Pi / 180))
+	sideways := Add(Sprod(right, px/distance), Sprod(down, py/distance))
+
+	return Add(Sprod(forward, cos), Sprod(sideways, sin)).Normalize(), true
+}
+
+// Map a point to a direction through the cube face it lies on. Each face spans
+// 90 degrees and is seen from the inside of the cube.
+func cubemapDirection(nx, ny float64, forward, right, down Vector) Vector {
+	column := int(nx * 3)
+	row := int(ny * 2)
+
+	if column > 2 {
+		column = 2
+	}
+
+	if row > 1 {
+		row = 1
+	}
+
+	// position on the face from -1 to 1
+	a := (nx*3-float64(column))*2 - 1
+	b := (ny*2-float64(row))*2 - 1
+
+	up := Sprod(down, -1)
+	back := Sprod(forward, -1)
+	left := Sprod(right, -1)
+
+	// center, right and down direction of each face
+	faces := [6][3]Vector{
+		{right, back, down},
+		{left, forward, down},
+		{up, right, forward},
+		{down, right, back},
+		{forward, right, down},
+		{back, left, down},
+	}
+
+	face := faces[row*3+column]
+
+	return Add(face[0], Add(Sprod(face[1], a), Sprod(face[2], b))).Normalize()
+}
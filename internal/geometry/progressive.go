@@ -0,0 +1,98 @@
+package geometry
+
+import (
+	"context"
+	"time"
+
+	"github.com/b-erhart/raytracer/internal/canvas"
+)
+
+// Limits of a progressive render. Rendering stops as soon as one of them is
+// reached. Zero values disable a limit.
+type ProgressiveOptions struct {
+	// Samples per pixel.
+	MaxSamples int
+	MaxTime    time.Duration
+	// Mean relative standard error of the pixel luminances.
+	NoiseThreshold float64
+}
+
+// Check whether at least one limit is set.
+func (o ProgressiveOptions) Enabled() bool {
+	return o.MaxSamples > 0 || o.MaxTime > 0 || o.NoiseThreshold > 0
+}
+
+// State of a progressive render after a completed pass.
+type PassStatus struct {
+	Pass            int
+	SamplesPerPixel int
+	// Mean relative standard error of the pixel luminances. Infinite until
+	// each pixel has at least two samples.
+	Noise   float64
+	Elapsed time.Duration
+}
+
+// Render the view progressively into the accumulator. Each pass adds one
+// jittered sample per pixel until a limit of the options is reached or ctx is
+// done. Passes are only added to the accumulator once they are complete, so
+// all pixels always have the same number of samples. The time limit does not
+// interrupt the first pass, so there is always an image. If onPass is not nil, it
+// is called from the calling goroutine after each pass, e.g. to write a
+// snapshot of the accumulator. Reaching the time limit is not an error; a
+// canceled context returns its error.
+func (r *Raytracer) RenderProgressive(ctx context.Context, view View, acc *canvas.Accumulator, options ProgressiveOptions, onPass func(PassStatus)) error {
+	if !options.Enabled() {
+		panic("progressive rendering requires at least one limit")
+	}
+
+	start := time.Now()
+	renderCtx := ctx
+
+	if options.MaxTime > 0 {
+		var cancel context.CancelFunc
+		renderCtx, cancel = context.WithDeadline(ctx, start.Add(options.MaxTime))
+		defer cancel()
+	}
+
+	status := PassStatus{}
+	pass := canvas.NewAccumulator(acc.Width(), acc.Height())
+
+	for options.MaxSamples <= 0 || status.SamplesPerPixel < options.MaxSamples {
+		passCtx := renderCtx
+		if status.Pass == 0 {
+			passCtx = ctx
+		}
+
+		pass.Reset()
+		err := r.renderTiles(passCtx, acc.Width(), acc.Height(), status.Pass, nil, func(w *worker, tile Tile) {
+			for j := tile.Y; j < tile.Y+tile.Height; j++ {
+				for i := tile.X; i < tile.X+tile.Width; i++ {
+					dx, dy := r.sampleOffset(w.rng, 0, 1)
+					r.addSample(w, view, pass, i, j, dx, dy)
+				}
+			}
+		})
+
+		// an incomplete pass is dropped
+		if err != nil {
+			return ctx.Err()
+		}
+
+		acc.Merge(pass)
+
+		status.Pass++
+		status.SamplesPerPixel++
+		status.Noise = acc.MeanNoise()
+		status.Elapsed = time.Since(start)
+
+		if onPass != nil {
+			onPass(status)
+		}
+
+		if options.NoiseThreshold > 0 && status.Noise <= options.NoiseThreshold {
+			break
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package geometry
+
+import "math"
+
+// Unit quaternion representing a rotation.
+type quaternion struct {
+	w, x, y, z float64
+}
+
+// Get the rotation of a transformation whose linear part is a rotation matrix.
+// Source: https://www.euclideanspace.com/maths/geometry/rotations/conversions/matrixToQuaternion/
+func rotationQuaternion(m Matrix) quaternion {
+	var q quaternion
+
+	switch trace := m[0][0] + m[1][1] + m[2][2]; {
+	case trace > 0:
+		s := 2 * math.Sqrt(trace+1)
+		q = quaternion{s / 4, (m[2][1] - m[1][2]) / s, (m[0][2] - m[2][0]) / s, (m[1][0] - m[0][1]) / s}
+	case m[0][0] > m[1][1] && m[0][0] > m[2][2]:
+		s := 2 * math.Sqrt(1+m[0][0]-m[1][1]-m[2][2])
+		q = quaternion{(m[2][1] - m[1][2]) / s, s / 4, (m[0][1] + m[1][0]) / s, (m[0][2] + m[2][0]) / s}
+	case m[1][1] > m[2][2]:
+		s := 2 * math.Sqrt(1+m[1][1]-m[0][0]-m[2][2])
+		q = quaternion{(m[0][2] - m[2][0]) / s, (m[0][1] + m[1][0]) / s, s / 4, (m[1][2] + m[2][1]) / s}
+	default:
+		s := 2 * math.Sqrt(1+m[2][2]-m[0][0]-m[1][1])
+		q = quaternion{(m[1][0] - m[0][1]) / s, (m[0][2] + m[2][0]) / s, (m[1][2] + m[2][1]) / s, s / 4}
+	}
+
+	return q.scaled(1 / math.Sqrt(q.dot(q)))
+}
+
+// Get the rotation as a transformation matrix.
+func (q quaternion) matrix() Matrix {
+	w, x, y, z := q.w, q.x, q.y, q.z
+
+	return Matrix{
+		{1 - 2*(y*y+z*z), 2 * (x*y - w*z), 2 * (x*z + w*y), 0},
+		{2 * (x*y + w*z), 1 - 2*(x*x+z*z), 2 * (y*z - w*x), 0},
+		{2 * (x*z - w*y), 2 * (y*z + w*x), 1 - 2*(x*x+y*y), 0},
+	}
+}
+
+func (q quaternion) dot(o quaternion) float64 {
+	return q.w*o.w + q.x*o.x + q.y*o.y + q.z*o.z
+}
+
+func (q quaternion) scaled(s float64) quaternion {
+	return quaternion{q.w * s, q.x * s, q.y * s, q.z * s}
+}
+
+func (q quaternion) plus(o quaternion) quaternion {
+	return quaternion{q.w + o.w, q.x + o.x, q.y + o.y, q.z + o.z}
+}
+
+// Interpolate between two rotations with t from 0 (a) to 1 (b), rotating at
+// constant speed the shorter way around. For rotations by half a turn, both
+// ways are equally short and one of them is taken.
+// Source: https://en.wikipedia.org/wiki/Slerp
+func slerp(a, b quaternion, t float64) quaternion {
+	cos := a.dot(b)
+	if cos < 0 {
+		b, cos = b.scaled(-1), -cos
+	}
+
+	// nearly identical rotations are interpolated linearly to avoid dividing
+	// by a sine close to 0
+	if cos > 0.9995 {
+		q := a.scaled(1 - t).plus(b.scaled(t))
+		return q.scaled(1 / math.Sqrt(q.dot(q)))
+	}
+
+	angle := math.Acos(cos)
+	sin := math.Sin(angle)
+
+	return a.scaled(math.Sin((1-t)*angle) / sin).plus(b.scaled(math.Sin(t*angle) / sin))
+}
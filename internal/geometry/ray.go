@@ -6,10 +6,17 @@ type Ray struct {
 	Origin    Vector
 	Direction Vector
 	Depth     int
+	// Point in time within the shutter interval, from 0 (open) to 1 (closed).
+	Time float64
+	// Participating medium and volume the ray travels through, if any.
+	Medium *Medium
+	Volume *Volume
 }
 
-func (r Ray) At(distance float64) Vector {
-	return Add(r.Origin, Sprod(r.Direction.Normalize(), distance))
+// Get the point at parameter t along the ray. For normalized ray directions, t
+// is the distance to the ray origin.
+func (r Ray) At(t float64) Vector {
+	return Add(r.Origin, Sprod(r.Direction, t))
 }
 
 func (r Ray) String() string {
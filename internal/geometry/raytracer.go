@@ -10,113 +10,224 @@ import (
 const Epsilon = 0.0000001
 
 type Raytracer struct {
-	objects    []Object
-	lights     []Light
-	background canvas.Color
-	bvhTree    BvhTree
+	objects             []Object
+	lights              []Light
+	background          Background
+	environment         Environment
+	fog                 Fog
+	transmissiveShadows bool
+	renderOptions       RenderOptions
+	antialiasing        AntialiasingOptions
+	bvhOptions          BvhOptions
+	bvh                 *FlatBvh
+	// whether any object is filled with a medium or is a volume
+	media bool
+	// workers reused by Trace, which is called outside of the render loops
+	workers sync.Pool
 }
 
-func NewRaytracer(objects []Object, lights []Light, background canvas.Color) *Raytracer {
-	return &Raytracer{objects, lights, background, ConstructBvhTree(objects)}
+func NewRaytracer(scene Scene) *Raytracer {
+	return &Raytracer{
+		objects:             scene.Objects,
+		lights:              scene.Lights,
+		background:          scene.Background,
+		environment:         scene.Environment,
+		fog:                 scene.Fog,
+		transmissiveShadows: scene.TransmissiveShadows,
+		renderOptions:       scene.RenderOptions,
+		antialiasing:        scene.Antialiasing,
+		bvhOptions:          scene.BvhOptions,
+		bvh:                 ConstructBvhTree(scene.Objects, scene.BvhOptions).Flatten(),
+		media:               containsMedia(scene.Objects),
+	}
+}
+
+// Replace the objects of the scene and rebuild the top-level bounding volume
+// hierarchy. Meshes referenced by instances keep their own hierarchy, so moving
+// instances around only rebuilds the top level.
+func (r *Raytracer) SetObjects(objects []Object) {
+	r.objects = objects
+	r.bvh = ConstructBvhTree(objects, r.bvhOptions).Flatten()
+	r.media = containsMedia(objects)
 }
 
-func (r *Raytracer) Render(view View, canv *canvas.Canvas) {
-	origin := view.Eye()
-	lineStart := view.BottomLeft()
+func containsMedia(objects []Object) bool {
+	for _, obj := range objects {
+		_, isVolume := obj.(*Volume)
+		if isVolume || obj.Props().Medium != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get the quality metrics of the top-level bounding volume hierarchy.
+func (r *Raytracer) BvhStats() BvhStats {
+	return r.bvh.Stats()
+}
 
-	var wg sync.WaitGroup
+// Get the color seen along a ray. It may be called concurrently.
+func (r *Raytracer) Trace(ray Ray) canvas.Color {
+	w, ok := r.workers.Get().(*worker)
+	if !ok {
+		w = newWorker(r.renderOptions.Seed)
+	}
+	defer r.workers.Put(w)
 
-	for j := 0; j < canv.Height(); j++ {
-		current := lineStart
+	return r.trace(w, ray)
+}
 
-		for i := 0; i < canv.Width(); i++ {
-			wg.Add(1)
+func (r *Raytracer) trace(w *worker, ray Ray) canvas.Color {
+	if ray.Depth >= 10 {
+		return canvas.Color{}
+	}
 
-			go func(i, j int, current Vector) {
-				defer wg.Done()
+	ray.Direction = ray.Direction.Normalize()
+	w.rays++
 
-				direction := Sub(current, origin).Normalize()
+	hit, found := r.bvh.ClosestHit(ray, math.Inf(1))
 
-				ray := Ray{
-					Origin:    origin,
-					Direction: direction,
-					Depth:     0,
-				}
+	distance := math.Inf(1)
+	if found {
+		distance = hit.Distance
+	}
 
-				canv.SetColor(i, j, r.Trace(ray))
-			}(i, j, current)
+	color := r.traceHit(w, ray, hit, found)
 
-			current = Add(current, view.Du())
-		}
+	if ray.Volume != nil {
+		color = ray.Volume.march(w, ray, distance, color)
+	}
 
-		lineStart = Add(lineStart, view.Dv())
+	if ray.Medium != nil {
+		color = r.throughMedium(w, ray, distance, color)
 	}
 
-	wg.Wait()
+	if r.fog.Enabled() {
+		color = r.fog.apply(ray, distance, color)
+	}
+
+	return color
 }
 
-func (r *Raytracer) Trace(ray Ray) canvas.Color {
-	if ray.Depth >= 10 {
+// Get the color seen at the end of a ray, which hits the given object if
+// found. Rays crossing the surface of an object filled with a medium or the
+// box of a volume continue behind it.
+func (r *Raytracer) traceHit(w *worker, ray Ray, hit Hit, found bool) canvas.Color {
+	if !found && ray.Depth == 0 && (r.background.custom() || !r.environment.Enabled()) {
+		return r.background.At(w.screenX, w.screenY)
+	} else if !found && r.environment.Enabled() {
+		return r.environment.Radiance(ray.Direction).Color()
+	} else if !found {
 		return canvas.Color{}
 	}
 
-	var closestObj Object
-	var tMin float64
+	// rays enter against the outward normal and leave along it
+	leaving := Dot(hit.Normal, ray.Direction) > 0
+
+	if volume, isVolume := hit.Object.(*Volume); isVolume {
+		next := volume
+		if leaving {
+			next = nil
+		}
+
+		color := r.trace(w, Ray{
+			Origin:    ray.At(hit.Distance),
+			Direction: ray.Direction,
+			Depth:     ray.Depth,
+			Time:      ray.Time,
+			Medium:    ray.Medium,
+			Volume:    next,
+		})
+
+		// rays starting inside the volume, e.g. at a camera within it, only
+		// meet it where they leave it
+		if leaving && ray.Volume != volume {
+			color = volume.march(w, ray, hit.Distance, color)
+		}
+
+		return color
+	}
+
+	if hit.Props.Medium != nil {
+		medium := hit.Props.Medium
+		if leaving {
+			medium = nil
+		}
 
-	relevantObjs := r.bvhTree.GetRelevantObjects(ray)
+		return r.trace(w, Ray{
+			Origin:    ray.At(hit.Distance),
+			Direction: ray.Direction,
+			Depth:     ray.Depth,
+			Time:      ray.Time,
+			Medium:    medium,
+			Volume:    ray.Volume,
+		})
+	}
 
-	for i := 0; i < len(relevantObjs); i++ {
-		intersects, t := relevantObjs[i].Intersection(ray)
+	color := r.shade(w, ray, hit)
 
-		if intersects && t >= Epsilon && (closestObj == nil || t < tMin) {
-			closestObj = relevantObjs[i]
-			tMin = t
+	if hit.Props.Transparency > 0 {
+		transmittedRay := Ray{
+			Origin:    ray.At(hit.Distance),
+			Direction: ray.Direction,
+			Depth:     ray.Depth + 1,
+			Time:      ray.Time,
+			Medium:    ray.Medium,
+			Volume:    ray.Volume,
 		}
+
+		transmission := r.trace(w, transmittedRay).Filter(hit.Props.Color)
+		color = color.Merge(transmission, hit.Props.Transparency)
 	}
 
-	if closestObj == nil && ray.Depth == 0 {
-		return r.background
-	} else if closestObj == nil {
-		return canvas.Color{}
-	} else if closestObj.Props().Reflectivity <= 0 {
-		return closestObj.Props().Color
+	return color
+}
+
+func (r *Raytracer) shade(w *worker, ray Ray, hit Hit) canvas.Color {
+	surface := hit.Props
+
+	if surface.Reflectivity <= 0 {
+		return surface.Color
 	}
 
-	surface := closestObj.Props()
 	color := surface.Color
-	point := ray.At(tMin)
-	normal := closestObj.SurfaceNormal(point)
+	point := ray.At(hit.Distance)
+	normal := hit.Normal
 
 	reflect := Sub(ray.Direction, Sprod(Sprod(normal, Dot(normal, ray.Direction)), 2))
 	reflectedRay := Ray{
 		Origin:    point,
 		Direction: reflect,
 		Depth:     ray.Depth + 1,
+		Time:      ray.Time,
+		Medium:    ray.Medium,
+		Volume:    ray.Volume,
+	}
+
+	// light scattered below the surface replaces the diffuse lighting
+	if surface.Subsurface != nil {
+		color = color.Merge(r.subsurfaceLight(w, ray, hit).Color(), surface.Reflectivity)
 	}
 
-Lights:
 	for i := 0; i < len(r.lights); i++ {
 		towardsLight := Sprod(r.lights[i].Direction, -1).Normalize()
 		rayToLight := Ray{
 			Origin:    point,
 			Direction: towardsLight,
 			Depth:     0,
+			Time:      ray.Time,
 		}
 
-		lightRelevantObjs := r.bvhTree.GetRelevantObjects(rayToLight)
-
-		for j := 0; j < len(lightRelevantObjs); j++ {
-			intersects, t := lightRelevantObjs[j].Intersection(rayToLight)
-
-			if intersects && t >= Epsilon {
-				continue Lights
-			}
+		lightColor, visible := r.incomingLight(w, rayToLight, math.Inf(1), r.lights[i].Color)
+		if !visible {
+			continue
 		}
 
-		ld := Dot(towardsLight, normal.Normalize())
+		ld := Dot(towardsLight, normal)
 
-		if ld > 0 {
-			color = color.Merge(r.lights[i].Color, ld*surface.Reflectivity)
+		if ld > 0 && surface.Subsurface == nil {
+			color = color.Merge(lightColor, ld*surface.Reflectivity)
 		}
 
 		spec := Dot(reflectedRay.Direction.Normalize(), towardsLight.Normalize())
@@ -124,12 +235,100 @@ Lights:
 		if spec > 0 {
 			spec = math.Pow(math.Pow(math.Pow(spec, 2), 2), 2)
 			spec *= surface.Specular
-			specColor := r.lights[i].Color.Mult(spec)
+			specColor := lightColor.Mult(spec)
 			color = color.Add(specColor)
 		}
 	}
 
-	reflection := r.Trace(reflectedRay)
+	if r.environment.Samples > 0 {
+		irradiance := r.environmentLight(w, point, normal, ray.Time)
+		color = color.Add(irradiance.Mul(surface.Color.Float()).Scale(surface.Reflectivity).Color())
+	}
+
+	reflection := r.trace(w, reflectedRay)
 
 	return color.Merge(reflection, surface.Mirror)
 }
+
+// Estimate the light of the environment map arriving at a point with the
+// given normal, weighted by the cosine of its angle to the normal and
+// normalized so that a uniform environment gives its own radiance. The
+// directions are importance sampled by the brightness of the map.
+func (r *Raytracer) environmentLight(w *worker, point, normal Vector, time float64) canvas.FloatColor {
+	var sum canvas.FloatColor
+
+	for i := 0; i < r.environment.Samples; i++ {
+		direction, radiance, pdf := r.environment.sample(w.rng)
+		if pdf <= 0 {
+			continue
+		}
+
+		cos := Dot(direction, normal)
+		if cos <= 0 {
+			continue
+		}
+
+		// the transmission of white light gives the share of the radiance that
+		// arrives, which may exceed the range of 8 bit colors
+		rayToLight := Ray{Origin: point, Direction: direction, Time: time}
+		transmission, visible := r.incomingLight(w, rayToLight, math.Inf(1), canvas.Color{R: 255, G: 255, B: 255})
+		if !visible {
+			continue
+		}
+
+		sum = sum.Add(radiance.Mul(transmission.Float()).Scale(cos / (math.Pi * pdf)))
+	}
+
+	return sum.Scale(1 / float64(r.environment.Samples))
+}
+
+// Get the light of a light source that arrives at the origin of a ray pointing
+// towards it, and whether any light arrives at all. Objects closer than
+// maxDistance block the light. With transmissive shadows, transparent objects
+// let it pass and tint it with their color instead. Media and volumes
+// attenuate it along the distance it travels through them.
+func (r *Raytracer) incomingLight(w *worker, rayToLight Ray, maxDistance float64, light canvas.Color) (canvas.Color, bool) {
+	w.rays++
+
+	if !r.transmissiveShadows && !r.media {
+		return light, !r.bvh.Occluded(rayToLight, maxDistance)
+	}
+
+	// hits arrive in any order, but the distance within a medium is the sum
+	// of the distances to where the ray leaves it minus the sum of the
+	// distances to where it enters it
+	opticalDepth := 0.0
+
+	blocked := r.bvh.AnyHit(rayToLight, maxDistance, func(hit Hit) bool {
+		// volumes report a single hit
+		if volume, isVolume := hit.Object.(*Volume); isVolume {
+			opticalDepth += volume.opticalDepth(rayToLight, maxDistance)
+			return false
+		}
+
+		if hit.Props.Medium != nil {
+			depth := hit.Props.Medium.extinction() * hit.Distance
+			if Dot(hit.Normal, rayToLight.Direction) < 0 {
+				depth = -depth
+			}
+
+			opticalDepth += depth
+
+			return false
+		}
+
+		if !r.transmissiveShadows || hit.Props.Transparency <= 0 {
+			return true
+		}
+
+		light = light.Filter(hit.Props.Color).Mult(hit.Props.Transparency)
+
+		return light == canvas.Color{}
+	})
+
+	if opticalDepth > 0 {
+		light = light.Mult(math.Exp(-opticalDepth))
+	}
+
+	return light, !blocked
+}
@@ -0,0 +1,197 @@
+package geometry
+
+import (
+	"context"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/b-erhart/raytracer/internal/canvas"
+)
+
+// Options for distributing rendering work.
+type RenderOptions struct {
+	// Number of goroutines rendering tiles.
+	Workers int
+	// Width and height of a tile in pixels.
+	TileSize  int
+	TileOrder TileOrder
+	// Seed of the random numbers for sampling. The random numbers of each
+	// tile are derived from it, so renders with the same seed are identical
+	// regardless of the number of workers and the order of the tiles.
+	Seed int64
+}
+
+// Get the default render options, using one worker per usable CPU.
+func DefaultRenderOptions() RenderOptions {
+	return RenderOptions{
+		Workers:   runtime.GOMAXPROCS(0),
+		TileSize:  32,
+		TileOrder: TileOrderScanline,
+	}
+}
+
+// Progress of a running render.
+type Progress struct {
+	CompletedTiles int
+	TotalTiles     int
+	// Share of completed tiles in percent.
+	Percent float64
+	// Number of traced rays, including reflection and shadow rays.
+	Rays          uint64
+	RaysPerSecond float64
+	Elapsed       time.Duration
+	// Estimated remaining time, based on the average time per tile so far.
+	ETA time.Duration
+}
+
+// State of a goroutine rendering tiles.
+type worker struct {
+	rays uint64
+	rng  *rand.Rand
+	// position of the current camera sample on the canvas, from 0 to 1
+	screenX float64
+	screenY float64
+}
+
+func newWorker(seed int64) *worker {
+	return &worker{
+		rng: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Get the seed of the random numbers for a tile in a pass over the image.
+func tileSeed(seed int64, pass int, tile Tile) int64 {
+	return hashInts(seed, pass, tile.X, tile.Y)
+}
+
+// Mix a seed with a number of integers. The inputs are mixed with the
+// finalizer of SplitMix64, so similar inputs give unrelated results.
+// Source: https://prng.di.unimi.it/splitmix64.c
+func hashInts(seed int64, values ...int) int64 {
+	z := uint64(seed)
+	for _, v := range values {
+		z += 0x9e3779b97f4a7c15 + uint64(v)
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		z ^= z >> 31
+	}
+
+	return int64(z)
+}
+
+// Render the view onto the canvas.
+func (r *Raytracer) Render(view View, canv *canvas.Canvas) {
+	r.RenderContext(context.Background(), view, canv, nil)
+}
+
+// Render the view onto the canvas. The canvas is split into tiles which a
+// fixed number of workers take from a shared queue. Once ctx is canceled or its
+// deadline passes, no further tiles are started and the context's error is
+// returned. If progress is not nil, it is called from the calling goroutine
+// after each completed tile.
+func (r *Raytracer) RenderContext(ctx context.Context, view View, canv *canvas.Canvas, progress func(Progress)) error {
+	acc := canvas.NewAccumulator(canv.Width(), canv.Height())
+
+	err := r.renderTiles(ctx, canv.Width(), canv.Height(), 0, progress, func(w *worker, tile Tile) {
+		for j := tile.Y; j < tile.Y+tile.Height; j++ {
+			for i := tile.X; i < tile.X+tile.Width; i++ {
+				r.renderPixel(w, view, acc, i, j)
+			}
+		}
+	})
+
+	for j := 0; j < canv.Height(); j++ {
+		for i := 0; i < canv.Width(); i++ {
+			canv.SetColor(i, j, acc.Color(i, j).Color())
+		}
+	}
+
+	return err
+}
+
+// Distribute the tiles of a width x height image among the workers, which
+// render them with renderTile. Each tile gets its own random numbers, which
+// also differ between passes over the same image.
+func (r *Raytracer) renderTiles(ctx context.Context, width, height, pass int, progress func(Progress), renderTile func(w *worker, tile Tile)) error {
+	options := r.renderOptions
+	if options.Workers <= 0 {
+		options.Workers = DefaultRenderOptions().Workers
+	}
+
+	if options.TileSize <= 0 {
+		options.TileSize = DefaultRenderOptions().TileSize
+	}
+
+	tiles := Tiles(width, height, options.TileSize, options.TileOrder)
+
+	queue := make(chan Tile, len(tiles))
+	for _, tile := range tiles {
+		queue <- tile
+	}
+	close(queue)
+
+	// number of rays traced for each completed tile
+	completed := make(chan uint64, options.Workers)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < options.Workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			w := newWorker(options.Seed)
+
+			for tile := range queue {
+				if ctx.Err() != nil {
+					return
+				}
+
+				w.rng.Seed(tileSeed(options.Seed, pass, tile))
+				raysBefore := w.rays
+				renderTile(w, tile)
+				completed <- w.rays - raysBefore
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(completed)
+	}()
+
+	start := time.Now()
+	status := Progress{TotalTiles: len(tiles)}
+
+	for rays := range completed {
+		status.CompletedTiles++
+		status.Rays += rays
+
+		if progress != nil {
+			status.update(time.Since(start))
+			progress(status)
+		}
+	}
+
+	if status.CompletedTiles == len(tiles) {
+		return nil
+	}
+
+	return ctx.Err()
+}
+
+func (p *Progress) update(elapsed time.Duration) {
+	p.Elapsed = elapsed
+	p.Percent = 100 * float64(p.CompletedTiles) / float64(p.TotalTiles)
+
+	if elapsed > 0 {
+		p.RaysPerSecond = float64(p.Rays) / elapsed.Seconds()
+	}
+
+	if p.CompletedTiles > 0 {
+		p.ETA = time.Duration(float64(elapsed) * float64(p.TotalTiles-p.CompletedTiles) / float64(p.CompletedTiles))
+	}
+}
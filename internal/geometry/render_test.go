@@ -0,0 +1,109 @@
+package geometry_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"sync"
+	"testing"
+
+	"github.com/b-erhart/raytracer/internal/canvas"
+	"github.com/b-erhart/raytracer/internal/geometry"
+)
+
+func TestRenderIsReproducible(t *testing.T) {
+	teapot := readTeapot(t)
+
+	antialiasing := geometry.DefaultAntialiasingOptions()
+	antialiasing.Samples = 2
+	antialiasing.Sampling = geometry.SamplingRandom
+
+	render := func(options geometry.RenderOptions) *image.RGBA {
+		raytracer, view := teapotScene(teapot, options, antialiasing)
+		canv := canvas.NewCanvas(160, 90)
+		raytracer.Render(view, canv)
+
+		return canv.Image()
+	}
+
+	first := render(geometry.RenderOptions{Workers: 1, TileSize: 16, TileOrder: geometry.TileOrderScanline, Seed: 7})
+	second := render(geometry.RenderOptions{Workers: 4, TileSize: 16, TileOrder: geometry.TileOrderHilbert, Seed: 7})
+	other := render(geometry.RenderOptions{Workers: 4, TileSize: 16, TileOrder: geometry.TileOrderHilbert, Seed: 8})
+
+	if !bytes.Equal(first.Pix, second.Pix) {
+		t.Error("renders with the same seed differ")
+	}
+
+	if bytes.Equal(first.Pix, other.Pix) {
+		t.Error("renders with different seeds are identical")
+	}
+}
+
+func BenchmarkRenderTiles(b *testing.B) {
+	teapot := readTeapot(b)
+
+	for _, tileSize := range []int{8, 16, 32, 64} {
+		for _, workers := range []int{1, 2, 4, 8} {
+			b.Run(fmt.Sprintf("tile=%d/workers=%d", tileSize, workers), func(b *testing.B) {
+				options := geometry.RenderOptions{Workers: workers, TileSize: tileSize}
+				raytracer, view := teapotScene(teapot, options, geometry.DefaultAntialiasingOptions())
+				b.ReportAllocs()
+
+				for i := 0; i < b.N; i++ {
+					raytracer.RenderContext(context.Background(), view, canvas.NewCanvas(160, 90), nil)
+				}
+			})
+		}
+	}
+}
+
+// Render with one goroutine per pixel and a single ray through each pixel, as
+// before the worker pool.
+func BenchmarkRenderPixelGoroutines(b *testing.B) {
+	raytracer, view := teapotScene(readTeapot(b), geometry.DefaultRenderOptions(), geometry.DefaultAntialiasingOptions())
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		canv := canvas.NewCanvas(160, 90)
+
+		var wg sync.WaitGroup
+
+		for y := 0; y < canv.Height(); y++ {
+			for x := 0; x < canv.Width(); x++ {
+				wg.Add(1)
+
+				go func(x, y int) {
+					defer wg.Done()
+
+					ray, _ := view.Ray(float64(x), float64(y))
+					canv.SetColor(x, y, raytracer.Trace(ray))
+				}(x, y)
+			}
+		}
+
+		wg.Wait()
+	}
+}
+
+// Get a raytracer for a lit, shiny teapot and a 160x90 view of it.
+func teapotScene(teapot *geometry.Mesh, options geometry.RenderOptions, antialiasing geometry.AntialiasingOptions) (*geometry.Raytracer, geometry.View) {
+	props := geometry.ObjectProps{
+		Color:        canvas.Color{R: 80, G: 200, B: 180},
+		Reflectivity: 0.8,
+		Mirror:       0.2,
+		Specular:     0.5,
+	}
+
+	raytracer := geometry.NewRaytracer(geometry.Scene{
+		Objects:       []geometry.Object{geometry.NewInstance(teapot, geometry.Identity(), props)},
+		Lights:        []geometry.Light{{Direction: geometry.Vector{X: 0.4, Y: -0.6, Z: 0.75}, Color: canvas.Color{R: 255, G: 255, B: 255}}},
+		BvhOptions:    geometry.DefaultBvhOptions(),
+		RenderOptions: options,
+		Antialiasing:  antialiasing,
+	})
+
+	view := geometry.NewView(160, 90, geometry.Vector{X: 0, Y: 1.5, Z: -8}, geometry.Vector{X: 0, Y: 0, Z: 1}, geometry.Vector{X: 0, Y: 1, Z: 0}, 45)
+
+	return raytracer, view
+}
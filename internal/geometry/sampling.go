@@ -0,0 +1,125 @@
+package geometry
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/b-erhart/raytracer/internal/canvas"
+)
+
+// Distribution of the samples within a pixel.
+type SamplingPattern int
+
+const (
+	// One random sample in each cell of a regular grid.
+	SamplingStratified SamplingPattern = iota
+	// Uniformly distributed random samples.
+	SamplingRandom
+)
+
+// Options for sampling and reconstructing pixels.
+type AntialiasingOptions struct {
+	// Samples along each axis of a pixel, i.e. every pixel gets Samples x
+	// Samples samples. A single sample is taken at the pixel center.
+	Samples  int
+	Sampling SamplingPattern
+	// Filter for reconstructing pixels from the samples. Each sample counts
+	// for all pixels within the filter radius, weighted by its offset from
+	// their centers.
+	Filter canvas.PixelFilter
+	// Refine pixels adaptively instead of taking a fixed number of samples if
+	// the sample limits are set.
+	Adaptive AdaptiveOptions
+}
+
+// Get the default antialiasing options, which take a single sample at the
+// center of each pixel.
+func DefaultAntialiasingOptions() AntialiasingOptions {
+	return AntialiasingOptions{
+		Samples:  1,
+		Sampling: SamplingStratified,
+		Filter:   canvas.NewBoxFilter(0.5),
+	}
+}
+
+// Add the configured number of samples of the pixel at (x, y) to the
+// accumulator. A single sample is taken at the center and only counts for the
+// pixel itself.
+func (r *Raytracer) renderPixel(w *worker, view View, acc *canvas.Accumulator, x, y int) {
+	samples := r.antialiasing.Samples * r.antialiasing.Samples
+
+	if samples <= 1 {
+		acc.AddSample(x, y, r.traceCamera(w, view, float64(x), float64(y)).Float(), 1)
+		return
+	}
+
+	for s := 0; s < samples; s++ {
+		dx, dy := r.sampleOffset(w.rng, s, samples)
+		r.addSample(w, view, acc, x, y, dx, dy)
+	}
+}
+
+// Trace a ray through the pixel at (x, y), offset from its center by (dx, dy),
+// and add it to all pixels within the filter radius, weighted by the filter.
+func (r *Raytracer) addSample(w *worker, view View, acc *canvas.Accumulator, x, y int, dx, dy float64) {
+	color := r.traceCamera(w, view, float64(x)+dx, float64(y)+dy)
+	acc.AddFilteredSample(x, y, dx, dy, color.Float(), r.filter())
+}
+
+// Get the offset from the pixel center of sample number index out of count
+// samples within the pixel.
+func (r *Raytracer) sampleOffset(rng *rand.Rand, index, count int) (float64, float64) {
+	if r.antialiasing.Sampling == SamplingRandom {
+		return rng.Float64() - 0.5, rng.Float64() - 0.5
+	}
+
+	return stratifiedOffset(rng, index, count)
+}
+
+// Get the color seen through the point (x, y) of the canvas, using a random
+// point on the lens and in time, darkened by vignetting. Points that the projection
+// does not cover are black.
+func (r *Raytracer) traceCamera(w *worker, view View, x, y float64) canvas.Color {
+	ray, covered := view.GenerateRay(CameraSample{
+		X:     x,
+		Y:     y,
+		LensU: w.rng.Float64(),
+		LensV: w.rng.Float64(),
+		Time:  w.rng.Float64(),
+	})
+
+	if !covered {
+		return canvas.Color{}
+	}
+
+	w.screenX = x / float64(view.width-1)
+	w.screenY = y / float64(view.height-1)
+	color := r.trace(w, ray)
+
+	if view.vignetting > 0 {
+		color = color.Mult(view.Vignetting(x, y))
+	}
+
+	return color
+}
+
+func (r *Raytracer) filter() canvas.PixelFilter {
+	if r.antialiasing.Filter == nil {
+		return DefaultAntialiasingOptions().Filter
+	}
+
+	return r.antialiasing.Filter
+}
+
+// Get the offset of sample number index out of count samples within a pixel.
+// The pixel is divided into a grid of strata which are filled in order, with a
+// random position inside each stratum. Offsets range from -0.5 to 0.5.
+func stratifiedOffset(rng *rand.Rand, index, count int) (float64, float64) {
+	strata := int(math.Ceil(math.Sqrt(float64(count))))
+	cell := index % (strata * strata)
+
+	x := (float64(cell%strata) + rng.Float64()) / float64(strata)
+	y := (float64(cell/strata) + rng.Float64()) / float64(strata)
+
+	return x - 0.5, y - 0.5
+}
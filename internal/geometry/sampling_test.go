@@ -0,0 +1,54 @@
+package geometry
+
+import (
+	"testing"
+
+	"github.com/b-erhart/raytracer/internal/canvas"
+)
+
+func TestFiltersKeepFlatPlane(t *testing.T) {
+	filters := map[string]canvas.PixelFilter{
+		"box":      canvas.NewBoxFilter(0.5),
+		"tent":     canvas.NewTentFilter(1),
+		"gaussian": canvas.NewGaussianFilter(1.5, 2),
+		"mitchell": canvas.NewMitchellFilter(2, 1.0/3, 1.0/3),
+		"lanczos":  canvas.NewLanczosFilter(2),
+	}
+
+	color := canvas.Color{R: 200, G: 100, B: 50}
+	props := ObjectProps{Color: color}
+	plane := []Object{
+		&Triangle{A: Vector{-1000, -1000, 5}, B: Vector{1000, -1000, 5}, C: Vector{1000, 1000, 5}, Properties: props},
+		&Triangle{A: Vector{-1000, -1000, 5}, B: Vector{1000, 1000, 5}, C: Vector{-1000, 1000, 5}, Properties: props},
+	}
+
+	for name, filter := range filters {
+		for _, sampling := range []SamplingPattern{SamplingStratified, SamplingRandom} {
+			antialiasing := DefaultAntialiasingOptions()
+			antialiasing.Samples = 2
+			antialiasing.Sampling = sampling
+			antialiasing.Filter = filter
+
+			raytracer := NewRaytracer(Scene{
+				Objects:       plane,
+				BvhOptions:    DefaultBvhOptions(),
+				RenderOptions: DefaultRenderOptions(),
+				Antialiasing:  antialiasing,
+			})
+
+			canv := canvas.NewCanvas(160, 90)
+			raytracer.Render(NewView(160, 90, Vector{0, 0, 0}, Vector{0, 0, 1}, Vector{0, 1, 0}, 45), canv)
+
+			image := canv.Image()
+
+			for y := 0; y < canv.Height(); y++ {
+				for x := 0; x < canv.Width(); x++ {
+					pixel := image.RGBAAt(x, y)
+					if got := (canvas.Color{R: pixel.R, G: pixel.G, B: pixel.B}); got != color {
+						t.Fatalf("%s filter, sampling %d: pixel (%d, %d) is %v, want %v", name, sampling, x, y, got, color)
+					}
+				}
+			}
+		}
+	}
+}
@@ -3,10 +3,41 @@ package geometry
 import "github.com/b-erhart/raytracer/internal/canvas"
 
 type Scene struct {
-	Canvas     *canvas.Canvas
-	View       View
-	Objects    []Object
-	Lights     []Light
-	Background canvas.Color
-	SSAA       bool
+	Canvas  *canvas.Canvas
+	View    View
+	Objects []Object
+	Lights  []Light
+	// Seen by camera rays that do not hit any object.
+	Background Background
+	// Surroundings of the scene, replacing a flat background color if enabled.
+	Environment   Environment
+	Fog           Fog
+	BvhOptions    BvhOptions
+	RenderOptions RenderOptions
+	// Render progressively if any limit is set.
+	Progressive  ProgressiveOptions
+	Antialiasing AntialiasingOptions
+	// Render a stereo pair if an interocular distance is set.
+	Stereo StereoOptions
+	// Let shadow rays pass through transparent objects, tinted by their color.
+	TransmissiveShadows bool
+}
+
+// Get the center of the bounding box of all objects. Without objects, this is
+// the center of the image plane.
+func (s Scene) Center() Vector {
+	if len(s.Objects) == 0 {
+		return Add(s.View.eye, s.View.lookAt)
+	}
+
+	bounds := s.Objects[0].extremes()
+	for _, obj := range s.Objects[1:] {
+		bounds = merge(bounds, obj.extremes())
+	}
+
+	return Vector{
+		(bounds.minX + bounds.maxX) / 2,
+		(bounds.minY + bounds.maxY) / 2,
+		(bounds.minZ + bounds.maxZ) / 2,
+	}
 }
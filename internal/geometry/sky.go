@@ -0,0 +1,172 @@
+package geometry
+
+import (
+	"math"
+
+	"github.com/b-erhart/raytracer/internal/canvas"
+)
+
+// Resolution of the environment map a sky is rendered into.
+const (
+	skyMapWidth  = 512
+	skyMapHeight = 256
+)
+
+// Luminance of the sky in kcd/m² that corresponds to a luminance of 1.
+const skyExposure = 20
+
+// Analytic daylight sky after Preetham et al., "A Practical Analytic Model
+// for Daylight" (1999). The sky does not contain the sun itself, which is a
+// separate light.
+type Sky struct {
+	// Angle of the sun above the horizon in radians.
+	SunElevation float64
+	// Angle of the sun around the y axis in radians, from the +z towards the
+	// +x direction.
+	SunAzimuth float64
+	// Haziness of the atmosphere, from 2 (clear) to about 10 (hazy).
+	Turbidity float64
+	// Share of the light at the horizon that the ground below reflects.
+	GroundAlbedo float64
+}
+
+// Coefficients of the Perez distribution function of one channel.
+type perezCoefficients [5]float64
+
+// Get the direction towards the sun.
+func (s Sky) SunDirection() Vector {
+	sinElevation, cosElevation := math.Sincos(s.SunElevation)
+	sinAzimuth, cosAzimuth := math.Sincos(s.SunAzimuth)
+
+	return Vector{cosElevation * sinAzimuth, sinElevation, cosElevation * cosAzimuth}
+}
+
+// Get a directional light for the sun, whose white light is reddened by the
+// atmosphere the lower the sun is.
+func (s Sky) Sun(intensity float64) Light {
+	zenith := math.Pi/2 - s.SunElevation
+
+	// relative optical air mass after Kasten and Young (1989)
+	airMass := 1 / (math.Cos(zenith) + 0.50572*math.Pow(96.07995-zenith*180/math.Pi, -1.6364))
+
+	// Rayleigh and aerosol optical depths at the wavelengths of red, green
+	// and blue in micrometers
+	beta := 0.04608*s.Turbidity - 0.04586
+	transmittance := func(wavelength float64) float64 {
+		rayleigh := 0.008735 * math.Pow(wavelength, -4.08)
+		aerosol := beta * math.Pow(wavelength, -1.3)
+
+		return math.Exp(-airMass * (rayleigh + aerosol))
+	}
+
+	color := canvas.FloatColor{
+		R: transmittance(0.68),
+		G: transmittance(0.55),
+		B: transmittance(0.44),
+	}
+
+	return Light{
+		Direction: Sprod(s.SunDirection(), -1),
+		Color:     color.Scale(intensity).Color(),
+	}
+}
+
+// Get the radiance of the sky in a direction. Below the horizon, the ground
+// reflects the light of the horizon.
+func (s Sky) Radiance(direction Vector) canvas.FloatColor {
+	d := direction.Normalize()
+	albedo := 1.0
+
+	if d.Y < 0 {
+		d = Vector{d.X, 0, d.Z}.Normalize()
+		albedo = s.GroundAlbedo
+	}
+
+	// the model diverges towards the horizon
+	theta := math.Min(math.Acos(math.Min(1, d.Y)), math.Pi/2-0.001)
+	gamma := math.Acos(math.Max(-1, math.Min(1, Dot(d, s.SunDirection()))))
+	thetaSun := math.Pi/2 - s.SunElevation
+
+	t := s.Turbidity
+	luminance := perezCoefficients{0.1787*t - 1.4630, -0.3554*t + 0.4275, -0.0227*t + 5.3251, 0.1206*t - 2.5771, -0.0670*t + 0.3703}
+	chromaX := perezCoefficients{-0.0193*t - 0.2592, -0.0665*t + 0.0008, -0.0004*t + 0.2125, -0.0641*t - 0.8989, -0.0033*t + 0.0452}
+	chromaY := perezCoefficients{-0.0167*t - 0.2608, -0.0950*t + 0.0092, -0.0079*t + 0.2102, -0.0441*t - 1.6537, -0.0109*t + 0.0529}
+
+	zenithX, zenithY := s.zenithChromaticity()
+
+	// luminance and CIE xy chromaticity
+	lum := s.zenithLuminance() / skyExposure * luminance.relative(theta, gamma, thetaSun)
+	x := zenithX * chromaX.relative(theta, gamma, thetaSun)
+	y := zenithY * chromaY.relative(theta, gamma, thetaSun)
+
+	return xyYToRgb(x, y, lum).Scale(albedo)
+}
+
+// Get the luminance of the zenith in kcd/m².
+func (s Sky) zenithLuminance() float64 {
+	chi := (4.0/9 - s.Turbidity/120) * (math.Pi - 2*(math.Pi/2-s.SunElevation))
+
+	return (4.0453*s.Turbidity-4.9710)*math.Tan(chi) - 0.2155*s.Turbidity + 2.4192
+}
+
+// Get the CIE xy chromaticity of the zenith.
+func (s Sky) zenithChromaticity() (float64, float64) {
+	t := s.Turbidity
+	theta := math.Pi/2 - s.SunElevation
+	theta2 := theta * theta
+	theta3 := theta2 * theta
+
+	x := t*t*(0.00166*theta3-0.00375*theta2+0.00209*theta) +
+		t*(-0.02903*theta3+0.06377*theta2-0.03202*theta+0.00394) +
+		(0.11693*theta3 - 0.21196*theta2 + 0.06052*theta + 0.25886)
+
+	y := t*t*(0.00275*theta3-0.00610*theta2+0.00317*theta) +
+		t*(-0.04214*theta3+0.08970*theta2-0.04153*theta+0.00516) +
+		(0.15346*theta3 - 0.26756*theta2 + 0.06670*theta + 0.26688)
+
+	return x, y
+}
+
+// Render the sky into an environment map, e.g. to light a scene with it.
+func (s Sky) EnvironmentMap() *EnvironmentMap {
+	image := canvas.NewHdrImage(skyMapWidth, skyMapHeight)
+
+	for y := 0; y < skyMapHeight; y++ {
+		sinTheta, cosTheta := math.Sincos(math.Pi * (float64(y) + 0.5) / skyMapHeight)
+
+		for x := 0; x < skyMapWidth; x++ {
+			sinPhi, cosPhi := math.Sincos(2 * math.Pi * ((float64(x)+0.5)/skyMapWidth - 0.5))
+			image.Set(x, y, s.Radiance(Vector{sinTheta * sinPhi, cosTheta, sinTheta * cosPhi}))
+		}
+	}
+
+	return NewEnvironmentMap(image)
+}
+
+// Evaluate the Perez distribution for a direction with zenith angle theta
+// and angle gamma to the sun, relative to its value at the zenith.
+func (p perezCoefficients) relative(theta, gamma, thetaSun float64) float64 {
+	perez := func(theta, gamma float64) float64 {
+		cosGamma := math.Cos(gamma)
+
+		return (1 + p[0]*math.Exp(p[1]/math.Cos(theta))) * (1 + p[2]*math.Exp(p[3]*gamma) + p[4]*cosGamma*cosGamma)
+	}
+
+	return perez(theta, gamma) / perez(0, thetaSun)
+}
+
+// Convert a CIE xyY color to linear sRGB.
+func xyYToRgb(x, y, luminance float64) canvas.FloatColor {
+	if y <= 0 {
+		return canvas.FloatColor{}
+	}
+
+	X := x / y * luminance
+	Z := (1 - x - y) / y * luminance
+
+	return canvas.FloatColor{
+		R: math.Max(0, 3.2406*X-1.5372*luminance-0.4986*Z),
+		G: math.Max(0, -0.9689*X+1.8758*luminance+0.0415*Z),
+		B: math.Max(0, 0.0557*X-0.2040*luminance+1.0570*Z),
+	}
+}
@@ -3,8 +3,10 @@ package geometry
 import "math"
 
 type Sphere struct {
-	Center           Vector
-	Radius           float64
+	Center Vector
+	Radius float64
+	// Distance the center moves during the shutter interval.
+	Motion           Vector
 	Properties       ObjectProps
 	extrmsCalculated bool
 	extrms           extremes
@@ -15,22 +17,26 @@ func (s *Sphere) Intersection(ray Ray) (bool, float64) {
 		return false, 0
 	}
 
+	// distances are calculated along the normalized direction and scaled back
+	// to the ray parameter afterwards
+	dirLength := ray.Direction.Length()
 	oc := Sub(ray.Origin, s.Center)
-	x := Dot(ray.Direction.Normalize(), oc)
+	x := Dot(Sprod(ray.Direction, 1/dirLength), oc)
 	e := math.Sqrt(x*x - (oc.Length()*oc.Length() - s.Radius*s.Radius))
 
 	t1 := -1*x + e
 	t2 := -1*x - e
 
+	// roots close to 0 belong to rays starting on the surface
 	switch {
-	case t1 < 0 && t2 < 0:
+	case t1 < Epsilon && t2 < Epsilon:
 		return false, 0
-	case t1 < 0:
-		return true, t2
-	case t2 < 0:
-		return true, t1
+	case t1 < Epsilon:
+		return true, t2 / dirLength
+	case t2 < Epsilon:
+		return true, t1 / dirLength
 	default:
-		return true, math.Min(t1, t2)
+		return true, math.Min(t1, t2) / dirLength
 	}
 }
 
@@ -42,6 +48,44 @@ func (s *Sphere) SurfaceNormal(point Vector) Vector {
 	return Sub(point, s.Center)
 }
 
+func (s *Sphere) hit(ray Ray, tMax float64) (Hit, bool) {
+	sphere := s.at(ray.Time)
+
+	intersects, t := sphere.Intersection(ray)
+	if !intersects || t < Epsilon || t >= tMax {
+		return Hit{}, false
+	}
+
+	return Hit{
+		Object:   s,
+		Distance: t,
+		Normal:   sphere.SurfaceNormal(ray.At(t)).Normalize(),
+		Props:    s.Properties,
+	}, true
+}
+
+func (s *Sphere) anyHit(ray Ray, tMax float64, accept func(Hit) bool) bool {
+	hit, intersects := s.hit(ray, tMax)
+	if !intersects || accept(hit) {
+		return intersects
+	}
+
+	// the distance through a medium is measured between both intersections
+	if s.Properties.Medium == nil {
+		return false
+	}
+
+	continued := Ray{Origin: ray.At(hit.Distance), Direction: ray.Direction, Time: ray.Time}
+	exit, intersects := s.hit(continued, tMax-hit.Distance)
+	if !intersects {
+		return false
+	}
+
+	exit.Distance += hit.Distance
+
+	return accept(exit)
+}
+
 func (s *Sphere) Props() ObjectProps {
 	return s.Properties
 }
@@ -54,14 +98,35 @@ func (s *Sphere) extremes() extremes {
 	return s.extrms
 }
 
+func (s *Sphere) motionExtremes() (extremes, extremes) {
+	return sphereExtremes(s.Center, s.Radius), sphereExtremes(Add(s.Center, s.Motion), s.Radius)
+}
+
 func (s *Sphere) calculateExtremes() {
-	s.extrms = extremes{
-		minX: s.Center.X - s.Radius,
-		minY: s.Center.Y - s.Radius,
-		minZ: s.Center.Z - s.Radius,
-		maxX: s.Center.X + s.Radius,
-		maxY: s.Center.Y + s.Radius,
-		maxZ: s.Center.Z + s.Radius,
-	}
+	start, end := s.motionExtremes()
+	s.extrms = merge(start, end)
 	s.extrmsCalculated = true
 }
+
+// Get the sphere at a point in time of the shutter interval.
+func (s *Sphere) at(time float64) *Sphere {
+	if s.Motion == (Vector{}) {
+		return s
+	}
+
+	return &Sphere{
+		Center: Add(s.Center, Sprod(s.Motion, time)),
+		Radius: s.Radius,
+	}
+}
+
+func sphereExtremes(center Vector, radius float64) extremes {
+	return extremes{
+		minX: center.X - radius,
+		minY: center.Y - radius,
+		minZ: center.Z - radius,
+		maxX: center.X + radius,
+		maxY: center.Y + radius,
+		maxZ: center.Z + radius,
+	}
+}
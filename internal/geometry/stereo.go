@@ -0,0 +1,80 @@
+package geometry
+
+import "github.com/b-erhart/raytracer/internal/canvas"
+
+// Alignment of the viewing directions of a stereo pair.
+type Convergence int
+
+const (
+	// Both eyes look along the viewing direction, so only objects at infinity
+	// have no parallax.
+	ConvergenceParallel Convergence = iota
+	// Both eyes are rotated to look at a point on the viewing direction.
+	ConvergenceToeIn
+)
+
+// Options for rendering a stereo pair.
+type StereoOptions struct {
+	// Distance between the eyes. Zero disables stereo rendering.
+	InterocularDistance float64
+	Convergence         Convergence
+	// Distance from the eye along the viewing direction at which toed-in eyes
+	// converge. Defaults to the focus distance of the lens, or the length of
+	// the lookAt vector without a lens.
+	ConvergenceDistance float64
+	// Arrangement of the two images in the output.
+	Composite canvas.StereoComposite
+}
+
+// Check whether stereo rendering is configured.
+func (o StereoOptions) Enabled() bool {
+	return o.InterocularDistance > 0
+}
+
+// Get the views of the left and the right eye, which are moved apart along
+// the horizontal image axis. Panoramic projections are not supported.
+func (v View) StereoPair(options StereoOptions) (View, View) {
+	if v.projection >= ProjectionEquirectangular {
+		panic("stereo rendering does not support panoramic projections")
+	}
+
+	return v.eyeView(-1, options), v.eyeView(1, options)
+}
+
+// Get the view of the left (side -1) or the right (side 1) eye.
+func (v View) eyeView(side float64, options StereoOptions) View {
+	view := v.reframed(v.eyeFrame(side, options))
+
+	// a moving camera keeps the eyes apart and converging for its whole
+	// motion, so the end of the motion gets the same offset and toe-in
+	// relative to the camera at that time
+	if v.motion != nil {
+		eye, lookAt := v.at(1).eyeFrame(side, options)
+		view.motion = &viewMotion{eye: eye, lookAt: lookAt}
+	}
+
+	return view
+}
+
+// Get the eye position and lookAt vector of one eye, moved along the
+// horizontal image axis of the view.
+func (v View) eyeFrame(side float64, options StereoOptions) (Vector, Vector) {
+	eye := Add(v.eye, Sprod(v.u, side*options.InterocularDistance/2))
+
+	if options.Convergence != ConvergenceToeIn {
+		return eye, v.lookAt
+	}
+
+	distance := options.ConvergenceDistance
+	if distance <= 0 {
+		distance = v.lens.FocusDistance
+	}
+
+	if distance <= 0 {
+		distance = v.lookAt.Length()
+	}
+
+	target := Add(v.eye, Sprod(v.lookAt.Normalize(), distance))
+
+	return eye, Sprod(Sub(target, eye).Normalize(), v.lookAt.Length())
+}
@@ -0,0 +1,21 @@
+package geometry
+
+import "testing"
+
+func TestMovingStereoPairKeepsConvergence(t *testing.T) {
+	options := StereoOptions{InterocularDistance: 0.5, Convergence: ConvergenceToeIn, ConvergenceDistance: 4}
+
+	start := NewView(64, 36, Vector{0, 0, 0}, Vector{0, 0, 1}, Vector{0, 1, 0}, 45)
+	end := NewView(64, 36, Vector{3, 1, 0}, Vector{1, 0, 0}, Vector{0, 1, 0}, 45)
+
+	left, right := start.WithMotion(end.eye, end.lookAt).StereoPair(options)
+	endLeft, endRight := end.StereoPair(options)
+
+	for _, pair := range [][2]View{{left.at(1), endLeft}, {right.at(1), endRight}} {
+		got, want := pair[0], pair[1]
+
+		if Sub(got.eye, want.eye).Length() > 1e-9 || Sub(got.lookAt, want.lookAt).Length() > 1e-9 {
+			t.Errorf("eye at the end of the motion is at %v looking along %v, want %v looking along %v", got.eye, got.lookAt, want.eye, want.lookAt)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package geometry
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/b-erhart/raytracer/internal/canvas"
+)
+
+// Maximum number of scattering events of a random walk.
+const maxSubsurfaceBounces = 64
+
+// Light transport below the surface of translucent materials like wax, soap,
+// marble or skin. Light enters the object, scatters inside and leaves it
+// elsewhere, which softens shading and lets light bleed into shadows and
+// through thin parts. The renderer has no path tracing mode yet, so the walks
+// replace the diffuse term of the Phong shading and gather the light of the
+// light sources where they leave the object.
+type Subsurface struct {
+	// Average distance light travels inside the material between two
+	// scattering events.
+	MeanFreePath float64
+	// Share of light of each channel that survives a scattering event. It
+	// gives the material its color.
+	Albedo canvas.Color
+	// Number of random walks per shading point.
+	Walks int
+}
+
+// Estimate the light of the light sources that enters the object below a
+// surface and leaves it at the given point. Each random walk starts at the
+// point, moves into the object and scatters at exponentially distributed
+// distances until it leaves the object, where it gathers the light arriving
+// at the surface. By reciprocity, this equals the light taking the reverse
+// path. Walks only intersect the object they entered, so objects inside or
+// overlapping it do not cut them short.
+// Source: https://pbr-book.org/4ed/Volume_Scattering/Volume_Scattering_Integrators
+func (r *Raytracer) subsurfaceLight(w *worker, ray Ray, hit Hit) canvas.FloatColor {
+	subsurface := hit.Props.Subsurface
+	albedo := subsurface.Albedo.Float()
+	walks := subsurface.Walks
+	if walks < 1 {
+		walks = 1
+	}
+
+	entered := hit.Root
+	if entered == nil {
+		entered = hit.Object
+	}
+
+	var sum canvas.FloatColor
+
+	for i := 0; i < walks; i++ {
+		walk := Ray{
+			Origin:    ray.At(hit.Distance),
+			Direction: cosineHemisphere(w.rng, Sprod(hit.Normal, -1)),
+			Time:      ray.Time,
+		}
+		throughput := canvas.FloatColor{R: 1, G: 1, B: 1}
+
+		for bounce := 0; bounce < maxSubsurfaceBounces; bounce++ {
+			distance := -math.Log(1-w.rng.Float64()) * subsurface.MeanFreePath
+
+			w.rays++
+			exit, found := entered.hit(walk, math.Inf(1))
+
+			// the walk can only miss the surface if the object is not closed
+			// or the walk started just outside of it, and then no light of
+			// this walk is known to leave at the surface
+			if !found {
+				break
+			}
+
+			if exit.Distance <= distance {
+				// the normal of the exit faces out of the object
+				normal := exit.Normal
+				if Dot(normal, walk.Direction) < 0 {
+					normal = Sprod(normal, -1)
+				}
+
+				sum = sum.Add(r.directLight(w, walk.At(exit.Distance), normal, ray.Time).Mul(throughput))
+				break
+			}
+
+			throughput = throughput.Mul(albedo)
+			if throughput.Luminance() < 0.001 {
+				break
+			}
+
+			walk = Ray{
+				Origin:    walk.At(distance),
+				Direction: uniformSphere(w.rng),
+				Time:      ray.Time,
+			}
+		}
+	}
+
+	return sum.Scale(1 / float64(walks))
+}
+
+// Get the light of the light sources arriving at a point, weighted by the
+// cosine of its angle to the normal.
+func (r *Raytracer) directLight(w *worker, point, normal Vector, time float64) canvas.FloatColor {
+	var sum canvas.FloatColor
+
+	for _, light := range r.lights {
+		towardsLight := Sprod(light.Direction, -1).Normalize()
+
+		cos := Dot(towardsLight, normal)
+		if cos <= 0 {
+			continue
+		}
+
+		rayToLight := Ray{Origin: point, Direction: towardsLight, Time: time}
+		lightColor, visible := r.incomingLight(w, rayToLight, math.Inf(1), light.Color)
+		if visible {
+			sum = sum.Add(lightColor.Float().Scale(cos))
+		}
+	}
+
+	return sum
+}
+
+// Get a random direction, distributed uniformly over the sphere.
+func uniformSphere(rng *rand.Rand) Vector {
+	z := 1 - 2*rng.Float64()
+	radius := math.Sqrt(math.Max(0, 1-z*z))
+	sin, cos := math.Sincos(2 * math.Pi * rng.Float64())
+
+	return Vector{radius * cos, radius * sin, z}
+}
+
+// Get a random direction in the hemisphere around a normal, distributed
+// proportionally to the cosine of its angle to the normal.
+func cosineHemisphere(rng *rand.Rand, normal Vector) Vector {
+	x, y := concentricDisk(rng.Float64(), rng.Float64())
+	z := math.Sqrt(math.Max(0, 1-x*x-y*y))
+
+	// any axis that is not parallel to the normal gives a tangent
+	axis := Vector{1, 0, 0}
+	if math.Abs(normal.X) > 0.9 {
+		axis = Vector{0, 1, 0}
+	}
+
+	tangent := Cross(normal, axis).Normalize()
+	bitangent := Cross(normal, tangent)
+
+	return Add(Add(Sprod(tangent, x), Sprod(bitangent, y)), Sprod(normal, z))
+}
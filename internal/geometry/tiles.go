@@ -0,0 +1,128 @@
+package geometry
+
+import (
+	"math"
+	"sort"
+)
+
+// Order in which the tiles of an image are rendered.
+type TileOrder int
+
+const (
+	// Row by row, starting at the first row of the canvas.
+	TileOrderScanline TileOrder = iota
+	// Outwards from the center of the image.
+	TileOrderSpiral
+	// Along a Hilbert curve, which keeps consecutive tiles close together.
+	TileOrderHilbert
+)
+
+// Rectangular region of a canvas.
+type Tile struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+// Split a canvas into tiles of (at most) size x size pixels, sorted in the
+// given order.
+func Tiles(width, height, size int, order TileOrder) []Tile {
+	if size <= 0 {
+		panic("tile size must be greater than 0")
+	}
+
+	columns := (width + size - 1) / size
+	rows := (height + size - 1) / size
+	tiles := make([]Tile, 0, columns*rows)
+
+	for row := 0; row < rows; row++ {
+		for column := 0; column < columns; column++ {
+			tile := Tile{
+				X:      column * size,
+				Y:      row * size,
+				Width:  size,
+				Height: size,
+			}
+
+			if tile.X+tile.Width > width {
+				tile.Width = width - tile.X
+			}
+
+			if tile.Y+tile.Height > height {
+				tile.Height = height - tile.Y
+			}
+
+			tiles = append(tiles, tile)
+		}
+	}
+
+	switch order {
+	case TileOrderSpiral:
+		sortTiles(tiles, size, spiralKey(columns, rows))
+	case TileOrderHilbert:
+		sortTiles(tiles, size, hilbertKey(columns, rows))
+	}
+
+	return tiles
+}
+
+func sortTiles(tiles []Tile, size int, key func(column, row int) float64) {
+	sort.SliceStable(tiles, func(i, j int) bool {
+		return key(tiles[i].X/size, tiles[i].Y/size) < key(tiles[j].X/size, tiles[j].Y/size)
+	})
+}
+
+// Order tiles by their ring around the center tile first and by their angle
+// within the ring second.
+func spiralKey(columns, rows int) func(column, row int) float64 {
+	centerColumn := float64(columns-1) / 2
+	centerRow := float64(rows-1) / 2
+
+	return func(column, row int) float64 {
+		dx := float64(column) - centerColumn
+		dy := float64(row) - centerRow
+		ring := math.Ceil(math.Max(math.Abs(dx), math.Abs(dy)))
+		angle := (math.Atan2(dy, dx) + math.Pi) / (2*math.Pi + Epsilon)
+
+		return ring + angle
+	}
+}
+
+// Order tiles by their distance along a Hilbert curve covering the tile grid.
+// Source: https://en.wikipedia.org/wiki/Hilbert_curve#Applications_and_mapping_algorithms
+func hilbertKey(columns, rows int) func(column, row int) float64 {
+	n := 1
+	for n < columns || n < rows {
+		n *= 2
+	}
+
+	return func(x, y int) float64 {
+		d := 0
+
+		for s := n / 2; s > 0; s /= 2 {
+			rx, ry := 0, 0
+
+			if x&s > 0 {
+				rx = 1
+			}
+
+			if y&s > 0 {
+				ry = 1
+			}
+
+			d += s * s * ((3 * rx) ^ ry)
+
+			if ry == 0 {
+				if rx == 1 {
+					x = n - 1 - x
+					y = n - 1 - y
+				}
+
+				x, y = y, x
+			}
+		}
+
+		return float64(d)
+	}
+}
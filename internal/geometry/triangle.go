@@ -63,6 +63,10 @@ func (t *Triangle) calculateEdges() {
 }
 
 func (t *Triangle) SurfaceNormal(point Vector) Vector {
+	if !t.NormalsSet {
+		return t.TriangleNormal()
+	}
+
 	bary := t.bary(point)
 
 	interpolated := Add(Add(Sprod(t.ASurfaceNormal, bary.X), Sprod(t.BSurfaceNormal, bary.Y)), Sprod(t.CSurfaceNormal, bary.Z))
@@ -77,6 +81,25 @@ func (t *Triangle) TriangleNormal() Vector {
 	return Cross(t.edge1, t.edge2).Normalize()
 }
 
+func (t *Triangle) hit(ray Ray, tMax float64) (Hit, bool) {
+	intersects, distance := t.Intersection(ray)
+	if !intersects || distance < Epsilon || distance >= tMax {
+		return Hit{}, false
+	}
+
+	return Hit{
+		Object:   t,
+		Distance: distance,
+		Normal:   t.SurfaceNormal(ray.At(distance)),
+		Props:    t.Properties,
+	}, true
+}
+
+func (t *Triangle) anyHit(ray Ray, tMax float64, accept func(Hit) bool) bool {
+	hit, intersects := t.hit(ray, tMax)
+	return intersects && accept(hit)
+}
+
 func (t *Triangle) Props() ObjectProps {
 	return t.Properties
 }
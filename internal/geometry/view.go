@@ -2,26 +2,122 @@ package geometry
 
 import "math"
 
+// Projection of a camera onto the canvas.
+type Projection int
+
+const (
+	// Rays start at the eye and pass through the image plane.
+	ProjectionPerspective Projection = iota
+	// Parallel rays along the viewing direction, starting on the plane through
+	// the eye.
+	ProjectionOrthographic
+	// Full sphere around the eye, with longitude along the x axis and
+	// latitude along the y axis of the canvas.
+	ProjectionEquirectangular
+	// Angular fisheye, i.e. the angle to the viewing direction grows linearly
+	// with the distance from the image center.
+	ProjectionFisheye
+	// Six cube faces in a 3x2 layout: right, left, up in the first row and
+	// down, front, back in the second.
+	ProjectionCubemap
+)
+
 type View struct {
-	eye    Vector
-	lookAt Vector
-	up     Vector
-	fov    float64
+	eye        Vector
+	lookAt     Vector
+	up         Vector
+	fov        float64
+	projection Projection
+	width      int
+	height     int
+	// width of the image plane
+	planeWidth float64
 
 	u          Vector
 	v          Vector
 	du         Vector
 	dv         Vector
 	bottomLeft Vector
+
+	lens       Lens
+	distortion Distortion
+	vignetting float64
+	// position at the end of the shutter interval, if the camera moves
+	motion *viewMotion
+}
+
+type viewMotion struct {
+	eye    Vector
+	lookAt Vector
+}
+
+// Thin lens of a camera, which blurs objects outside of the focus plane.
+type Lens struct {
+	// Radius of the aperture. Zero gives a pinhole camera with everything in
+	// focus.
+	Aperture float64
+	// Distance from the eye to the plane in focus, along the viewing
+	// direction.
+	FocusDistance float64
+	// Number of aperture blades, which shape out-of-focus highlights. Less than
+	// 3 gives a circular aperture.
+	Blades int
+	// Rotation of the blades in radians.
+	BladeRotation float64
+}
+
+// Position of a camera sample on the canvas and on the lens.
+type CameraSample struct {
+	// Point on the canvas. Integer coordinates hit the sample position of the
+	// corresponding pixel.
+	X float64
+	Y float64
+	// Numbers in [0, 1) that select the point on the lens.
+	LensU float64
+	LensV float64
+	// Point in time within the shutter interval, from 0 to 1.
+	Time float64
 }
 
 func NewView(canvWidth, canvHeight int, eye, lookAt, up Vector, fov float64) View {
+	view := newView(canvWidth, canvHeight, eye, lookAt, up, math.Tan(fov*(math.Pi/180)))
+	view.fov = fov
+
+	return view
+}
+
+// Create an orthographic view framed like a perspective one, which covers
+// viewWidth units horizontally instead of a field of view.
+func NewOrthographicView(canvWidth, canvHeight int, eye, lookAt, up Vector, viewWidth float64) View {
+	view := newView(canvWidth, canvHeight, eye, lookAt, up, viewWidth)
+	view.projection = ProjectionOrthographic
+
+	return view
+}
+
+// Create a view covering the surroundings of the eye with a panoramic
+// projection. The field of view in degrees only applies to fisheye views.
+func NewPanoramicView(canvWidth, canvHeight int, eye, lookAt, up Vector, projection Projection, fov float64) View {
+	if projection < ProjectionEquirectangular {
+		panic("projection is not panoramic")
+	}
+
+	view := newView(canvWidth, canvHeight, eye, lookAt, up, 1)
+	view.projection = projection
+	view.fov = fov
+
+	return view
+}
+
+func newView(canvWidth, canvHeight int, eye, lookAt, up Vector, uLen float64) View {
 	var view View
 
 	view.eye = eye
 	view.lookAt = lookAt
 	view.up = up
-	view.fov = fov
+	view.width = canvWidth
+	view.height = canvHeight
+	view.planeWidth = uLen
 
 	lxup := Cross(lookAt, up)
 	view.u = Sprod(lxup, -1/lxup.Length()).Normalize()
@@ -31,7 +127,6 @@ func NewView(canvWidth, canvHeight int, eye, lookAt, up Vector, fov float64) Vie
 
 	aspectRatio := float64(canvHeight) / float64(canvWidth)
 
-	uLen := math.Tan(fov * (math.Pi / 180))
 	vLen := uLen * aspectRatio
 
 	view.du = Sprod(view.u, uLen/float64(canvWidth-1))
@@ -60,6 +155,10 @@ func (v View) Fov() float64 {
 	return v.fov
 }
 
+func (v View) Projection() Projection {
+	return v.projection
+}
+
 func (v View) U() Vector {
 	return v.u
 }
@@ -79,3 +178,190 @@ func (v View) Dv() Vector {
 func (v View) BottomLeft() Vector {
 	return v.bottomLeft
 }
+
+func (v View) Lens() Lens {
+	return v.lens
+}
+
+// Get a copy of the view that uses the given lens.
+func (v View) WithLens(lens Lens) View {
+	v.lens = lens
+
+	return v
+}
+
+// Get a copy of the view that moves during the shutter interval, from its
+// current eye and lookAt vector to the given ones.
+func (v View) WithMotion(endEye, endLookAt Vector) View {
+	v.motion = &viewMotion{eye: endEye, lookAt: endLookAt}
+
+	return v
+}
+
+// Get a copy of the view with another eye and lookAt vector, keeping all other
+// settings.
+func (v View) reframed(eye, lookAt Vector) View {
+	view := newView(v.width, v.height, eye, lookAt, v.up, v.planeWidth)
+	view.fov = v.fov
+	view.projection = v.projection
+	view.lens = v.lens
+	view.distortion = v.distortion
+	view.vignetting = v.vignetting
+	view.motion = v.motion
+
+	return view
+}
+
+// Get a copy of the view that is rotated by an angle in radians around the
+// axis through center along the up vector, e.g. to orbit around a scene.
+func (v View) Orbit(center Vector, angle float64) View {
+	rotation := Mul(Translation(center), Mul(Rotation(v.up, angle), Translation(Sprod(center, -1))))
+
+	view := v.reframed(rotation.Point(v.eye), rotation.Direction(v.lookAt))
+
+	if v.motion != nil {
+		view.motion = &viewMotion{
+			eye:    rotation.Point(v.motion.eye),
+			lookAt: rotation.Direction(v.motion.lookAt),
+		}
+	}
+
+	return view
+}
+
+// Get the view at a point in time of the shutter interval.
+func (v View) at(time float64) View {
+	if v.motion == nil {
+		return v
+	}
+
+	eye := Add(v.eye, Sprod(Sub(v.motion.eye, v.eye), time))
+	lookAt := Add(v.lookAt, Sprod(Sub(v.motion.lookAt, v.lookAt), time))
+
+	view := v.reframed(eye, lookAt)
+	view.motion = nil
+
+	return view
+}
+
+// Get the distance of a point from the eye along the viewing direction, e.g.
+// to focus the lens on it.
+func (v View) DistanceTo(point Vector) float64 {
+	return Dot(Sub(point, v.eye), v.lookAt.Normalize())
+}
+
+// Get the camera ray through the point (x, y) of the canvas, passing through
+// the center of the lens, and whether the projection covers the point.
+func (v View) Ray(x, y float64) (Ray, bool) {
+	return v.GenerateRay(CameraSample{X: x, Y: y, LensU: 0.5, LensV: 0.5})
+}
+
+// Get the camera ray for a sample and whether the projection covers the
+// sampled point of the canvas. With an aperture, the ray starts at the sampled
+// point on the lens and passes through the point of the focus plane that the
+// ray through the lens center would hit. Panoramic projections ignore the
+// lens. The ray gets the time of the sample, at which a moving camera is
+// positioned.
+func (v View) GenerateRay(sample CameraSample) (Ray, bool) {
+	ray, covered := v.at(sample.Time).generateRay(sample)
+	ray.Time = sample.Time
+
+	return ray, covered
+}
+
+func (v View) generateRay(sample CameraSample) (Ray, bool) {
+	if v.projection >= ProjectionEquirectangular {
+		direction, covered := v.panoramaDirection(sample.X, sample.Y)
+
+		return Ray{
+			Origin:    v.eye,
+			Direction: direction,
+			Depth:     0,
+		}, covered
+	}
+
+	target := v.undistort(Add(v.bottomLeft, Add(Sprod(v.du, sample.X), Sprod(v.dv, sample.Y))))
+	center := v.eye
+	direction := Sub(target, v.eye).Normalize()
+
+	if v.projection == ProjectionOrthographic {
+		center = Sub(target, v.lookAt)
+		direction = v.lookAt.Normalize()
+	}
+
+	if v.lens.Aperture <= 0 {
+		return Ray{
+			Origin:    center,
+			Direction: direction,
+			Depth:     0,
+		}, true
+	}
+
+	focusDistance := v.lens.FocusDistance
+	if focusDistance <= 0 {
+		focusDistance = v.lookAt.Length()
+	}
+
+	focus := Add(center, Sprod(direction, focusDistance/Dot(direction, v.lookAt.Normalize())))
+
+	lensX, lensY := v.lens.samplePoint(sample.LensU, sample.LensV)
+	origin := Add(center, Add(Sprod(v.u, lensX), Sprod(v.v, lensY)))
+
+	return Ray{
+		Origin:    origin,
+		Direction: Sub(focus, origin).Normalize(),
+		Depth:     0,
+	}, true
+}
+
+// Map two numbers in [0, 1) to a uniformly distributed point on the aperture.
+func (l Lens) samplePoint(u, v float64) (float64, float64) {
+	if l.Blades < 3 {
+		x, y := concentricDisk(u, v)
+
+		return x * l.Aperture, y * l.Aperture
+	}
+
+	// pick one of the triangles between the center and two neighboring
+	// corners, then reuse the remaining part of u within that triangle
+	sector := float64(int(u * float64(l.Blades)))
+	u = u*float64(l.Blades) - sector
+
+	angle := 2 * math.Pi / float64(l.Blades)
+	y1, x1 := math.Sincos(l.BladeRotation + sector*angle)
+	y2, x2 := math.Sincos(l.BladeRotation + (sector+1)*angle)
+
+	// uniform point in the triangle (0, corner 1, corner 2)
+	// Source: https://pbr-book.org/3ed-2018/Monte_Carlo_Integration/2D_Sampling_with_Multidimensional_Transformations#SamplingaTriangle
+	root := math.Sqrt(u)
+	a := root * (1 - v)
+	b := root * v
+
+	return (a*x1 + b*x2) * l.Aperture, (a*y1 + b*y2) * l.Aperture
+}
+
+// Map two numbers in [0, 1) to a uniformly distributed point on the unit disk,
+// keeping neighboring inputs close together.
+// Source: https://pbr-book.org/3ed-2018/Monte_Carlo_Integration/2D_Sampling_with_Multidimensional_Transformations#ConcentricSampleDisk
+func concentricDisk(u, v float64) (float64, float64) {
+	x := 2*u - 1
+	y := 2*v - 1
+
+	if x == 0 && y == 0 {
+		return 0, 0
+	}
+
+	var radius, theta float64
+
+	if math.Abs(x) > math.Abs(y) {
+		radius = x
+		theta = math.Pi / 4 * (y / x)
+	} else {
+		radius = y
+		theta = math.Pi/2 - math.Pi/4*(x/y)
+	}
+
+	sin, cos := math.Sincos(theta)
+
+	return radius * cos, radius * sin
+}
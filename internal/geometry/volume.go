@@ -0,0 +1,241 @@
+package geometry
+
+import (
+	"math"
+	"sort"
+
+	"github.com/b-erhart/raytracer/internal/canvas"
+)
+
+// Dense grid of density values, e.g. from a simulation.
+type VoxelGrid struct {
+	nx int
+	ny int
+	nz int
+	// values with x varying fastest, then y, then z
+	densities []float64
+}
+
+// Point of a transfer function, which maps densities to emitted color and
+// opacity.
+type TransferPoint struct {
+	Density float64
+	Color   canvas.Color
+	// Share of light absorbed per unit of distance.
+	Opacity float64
+}
+
+// Heterogeneous volume given by a voxel grid stretched over an axis-aligned
+// box. Rays through the box are ray marched, accumulating the color and
+// opacity assigned to the densities by a transfer function.
+type Volume struct {
+	Min  Vector
+	Max  Vector
+	Grid *VoxelGrid
+	// Points of the transfer function, sorted by density. Values in between
+	// are interpolated linearly, values outside are clamped.
+	Transfer []TransferPoint
+	// Distance between samples along a ray.
+	StepSize float64
+}
+
+// Create a voxel grid with the given number of voxels along each axis.
+func NewVoxelGrid(nx, ny, nz int, densities []float64) *VoxelGrid {
+	if nx <= 0 || ny <= 0 || nz <= 0 || len(densities) != nx*ny*nz {
+		panic("voxel grid size does not match the number of densities")
+	}
+
+	return &VoxelGrid{nx: nx, ny: ny, nz: nz, densities: densities}
+}
+
+// Get the number of voxels along each axis.
+func (g *VoxelGrid) Resolution() (int, int, int) {
+	return g.nx, g.ny, g.nz
+}
+
+// Get the trilinearly interpolated density at a point with coordinates from
+// 0 to 1 within the grid. Voxel values lie at the centers of the voxels.
+func (g *VoxelGrid) densityAt(p Vector) float64 {
+	x, fx, x1 := gridCoordinate(p.X, g.nx)
+	y, fy, y1 := gridCoordinate(p.Y, g.ny)
+	z, fz, z1 := gridCoordinate(p.Z, g.nz)
+
+	at := func(x, y, z int) float64 {
+		return g.densities[(z*g.ny+y)*g.nx+x]
+	}
+
+	lerp := func(a, b, t float64) float64 {
+		return a + (b-a)*t
+	}
+
+	front := lerp(lerp(at(x, y, z), at(x1, y, z), fx), lerp(at(x, y1, z), at(x1, y1, z), fx), fy)
+	back := lerp(lerp(at(x, y, z1), at(x1, y, z1), fx), lerp(at(x, y1, z1), at(x1, y1, z1), fx), fy)
+
+	return lerp(front, back, fz)
+}
+
+// Get the index of the voxel before a coordinate from 0 to 1, the fraction
+// of the way to the next voxel and the index of the next voxel.
+func gridCoordinate(coordinate float64, count int) (int, float64, int) {
+	g := math.Max(0, math.Min(float64(count-1), coordinate*float64(count)-0.5))
+	i := int(g)
+
+	next := i + 1
+	if next >= count {
+		next = count - 1
+	}
+
+	return i, g - float64(i), next
+}
+
+// Get the emitted color and the opacity of a density.
+func (v *Volume) transfer(density float64) (canvas.FloatColor, float64) {
+	points := v.Transfer
+	if len(points) == 0 {
+		return canvas.FloatColor{}, 0
+	}
+
+	i := sort.Search(len(points), func(i int) bool {
+		return points[i].Density > density
+	})
+
+	if i == 0 {
+		return points[0].Color.Float(), points[0].Opacity
+	} else if i == len(points) {
+		return points[i-1].Color.Float(), points[i-1].Opacity
+	}
+
+	from, to := points[i-1], points[i]
+	t := (density - from.Density) / (to.Density - from.Density)
+	color := from.Color.Float().Scale(1 - t).Add(to.Color.Float().Scale(t))
+
+	return color, from.Opacity + (to.Opacity-from.Opacity)*t
+}
+
+// Get the interval of ray parameters within the box, which may start behind
+// the ray origin.
+func (v *Volume) interval(ray Ray) (float64, float64, bool) {
+	near, far := math.Inf(-1), math.Inf(1)
+	origin := [3]float64{ray.Origin.X, ray.Origin.Y, ray.Origin.Z}
+	direction := [3]float64{ray.Direction.X, ray.Direction.Y, ray.Direction.Z}
+	min := [3]float64{v.Min.X, v.Min.Y, v.Min.Z}
+	max := [3]float64{v.Max.X, v.Max.Y, v.Max.Z}
+
+	for axis := 0; axis < 3; axis++ {
+		t1 := (min[axis] - origin[axis]) / direction[axis]
+		t2 := (max[axis] - origin[axis]) / direction[axis]
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+
+		// rays parallel to a slab give NaN if they start on its border
+		if !math.IsNaN(t1) {
+			near = math.Max(near, t1)
+		}
+
+		if !math.IsNaN(t2) {
+			far = math.Min(far, t2)
+		}
+	}
+
+	return near, far, near <= far && far >= Epsilon
+}
+
+func (v *Volume) hit(ray Ray, tMax float64) (Hit, bool) {
+	near, far, intersects := v.interval(ray)
+	if !intersects {
+		return Hit{}, false
+	}
+
+	// rays starting inside the box hit it where they leave it, and the normal
+	// points along rays leaving the box and against rays entering it
+	t := near
+	normal := Sprod(ray.Direction, -1).Normalize()
+	if near < Epsilon {
+		t = far
+		normal = ray.Direction.Normalize()
+	}
+
+	if t >= tMax {
+		return Hit{}, false
+	}
+
+	return Hit{
+		Object:   v,
+		Distance: t,
+		Normal:   normal,
+	}, true
+}
+
+func (v *Volume) anyHit(ray Ray, tMax float64, accept func(Hit) bool) bool {
+	hit, intersects := v.hit(ray, tMax)
+	return intersects && accept(hit)
+}
+
+func (v *Volume) Props() ObjectProps {
+	return ObjectProps{}
+}
+
+func (v *Volume) extremes() extremes {
+	return extremes{
+		minX: v.Min.X,
+		minY: v.Min.Y,
+		minZ: v.Min.Z,
+		maxX: v.Max.X,
+		maxY: v.Max.Y,
+		maxZ: v.Max.Z,
+	}
+}
+
+// Get the density at a point within the box.
+func (v *Volume) densityAt(p Vector) float64 {
+	size := Sub(v.Max, v.Min)
+	local := Sub(p, v.Min)
+
+	return v.Grid.densityAt(Vector{local.X / size.X, local.Y / size.Y, local.Z / size.Z})
+}
+
+// Get the color seen along a ray segment of the given length through the
+// volume, which ends in the given color. The ray starts inside the box.
+func (v *Volume) march(w *worker, ray Ray, distance float64, color canvas.Color) canvas.Color {
+	_, far, _ := v.interval(ray)
+	length := math.Min(distance, far)
+
+	transmittance := 1.0
+	var emitted canvas.FloatColor
+
+	// jittering the samples turns banding into noise
+	offset := w.rng.Float64()
+
+	for start := 0.0; start < length && transmittance > 0.001; start += v.StepSize {
+		step := math.Min(v.StepSize, length-start)
+		emission, opacity := v.transfer(v.densityAt(ray.At(start + offset*step)))
+
+		alpha := 1 - math.Exp(-opacity*step)
+		emitted = emitted.Add(emission.Scale(transmittance * alpha))
+		transmittance *= 1 - alpha
+	}
+
+	return color.Float().Scale(transmittance).Add(emitted).Color()
+}
+
+// Get the optical depth of the volume along a ray up to a ray parameter, e.g.
+// to attenuate light on its way to a point.
+func (v *Volume) opticalDepth(ray Ray, tMax float64) float64 {
+	near, far, intersects := v.interval(ray)
+	if !intersects {
+		return 0
+	}
+
+	near = math.Max(near, 0)
+	far = math.Min(far, tMax)
+	depth := 0.0
+
+	for start := near; start < far; start += v.StepSize {
+		step := math.Min(v.StepSize, far-start)
+		_, opacity := v.transfer(v.densityAt(ray.At(start + step/2)))
+		depth += opacity * step
+	}
+
+	return depth
+}
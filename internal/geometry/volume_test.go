@@ -0,0 +1,46 @@
+package geometry
+
+import (
+	"testing"
+
+	"github.com/b-erhart/raytracer/internal/canvas"
+)
+
+func TestCameraInsideVolume(t *testing.T) {
+	densities := make([]float64, 4*4*4)
+	for i := range densities {
+		densities[i] = 1
+	}
+
+	volume := &Volume{
+		Min:  Vector{-5, -5, -5},
+		Max:  Vector{5, 5, 5},
+		Grid: NewVoxelGrid(4, 4, 4, densities),
+		Transfer: []TransferPoint{
+			{Density: 0},
+			{Density: 1, Color: canvas.Color{R: 255}, Opacity: 2},
+		},
+		StepSize: 0.1,
+	}
+
+	raytracer := NewRaytracer(Scene{
+		Objects:       []Object{volume},
+		BvhOptions:    DefaultBvhOptions(),
+		RenderOptions: DefaultRenderOptions(),
+		Antialiasing:  DefaultAntialiasingOptions(),
+	})
+
+	canv := canvas.NewCanvas(32, 18)
+	raytracer.Render(NewView(32, 18, Vector{1, 0.5, -2}, Vector{0, 0, 1}, Vector{0, 1, 0}, 45), canv)
+
+	// the camera sees at least 3 units of fully red volume in every direction
+	image := canv.Image()
+
+	for y := 0; y < canv.Height(); y++ {
+		for x := 0; x < canv.Width(); x++ {
+			if pixel := image.RGBAAt(x, y); pixel.R < 240 || pixel.G != 0 || pixel.B != 0 {
+				t.Fatalf("pixel (%d, %d) is %v, want red", x, y, pixel)
+			}
+		}
+	}
+}
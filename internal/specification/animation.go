@@ -0,0 +1,331 @@
+package specification
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/b-erhart/raytracer/internal/canvas"
+	"github.com/b-erhart/raytracer/internal/geometry"
+)
+
+// Image specification whose values may change over the frames of an
+// animation. Files like wavefront meshes are read once and shared by the
+// scenes of all frames, so only the top-level hierarchy is rebuilt per frame.
+type Animation struct {
+	spec      ImageSpec
+	path      string
+	resources *sceneResources
+}
+
+// Contents of the files referenced by a specification, by absolute path.
+type sceneResources struct {
+	meshes       map[string]*geometry.Mesh
+	environments map[string]*geometry.EnvironmentMap
+	grids        map[string]*geometry.VoxelGrid
+}
+
+type AnimationSpec struct {
+	// Number of frames, which are numbered from 0.
+	Frames       int
+	Camera       CameraTracks
+	Lights       []LightTracks
+	Spheres      []SphereTracks
+	Models       []ModelTracks
+	SurfaceProps []SurfacePropTracks
+}
+
+type CameraTracks struct {
+	Position []Keyframe[geometry.Vector]
+	LookAt   []Keyframe[geometry.Vector]
+	Up       []Keyframe[geometry.Vector]
+	Fov      []Keyframe[float64]
+}
+
+type LightTracks struct {
+	// Index of the light in the lights of the specification.
+	Index     int
+	Direction []Keyframe[geometry.Vector]
+	Color     []Keyframe[canvas.Color]
+}
+
+type SphereTracks struct {
+	// Index of the sphere in the spheres of the specification.
+	Index  int
+	Center []Keyframe[geometry.Vector]
+	Radius []Keyframe[float64]
+}
+
+type ModelTracks struct {
+	// Index of the model in the models of the specification.
+	Index    int
+	Center   []Keyframe[geometry.Vector]
+	Rotation []Keyframe[geometry.Vector]
+	Size     []Keyframe[float64]
+}
+
+type SurfacePropTracks struct {
+	Name  string
+	Color []Keyframe[canvas.Color]
+}
+
+// Value of an animated property at a frame. Between two keyframes, the value
+// is interpolated as configured by the first one. Before the first and after
+// the last keyframe, the value stays constant.
+type Keyframe[T any] struct {
+	Frame float64
+	Value T
+	// Either "linear" (default) or "bezier".
+	Interpolation string
+	// Control points (x1, y1, x2, y2) of the cubic Bezier curve that maps the
+	// elapsed share of time to the share of the value change, like CSS timing
+	// functions. Defaults to ease-in-out.
+	Ease *[4]float64
+}
+
+var defaultEase = [4]float64{0.42, 0, 0.58, 1}
+
+// Read an image specification file, which may contain an animation.
+func ReadAnimationFromSpecFile(path string) (*Animation, error) {
+	spec, err := readSpecFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image specification: %w", err)
+	}
+
+	return &Animation{
+		spec: spec,
+		path: path,
+		resources: &sceneResources{
+			meshes:       make(map[string]*geometry.Mesh),
+			environments: make(map[string]*geometry.EnvironmentMap),
+			grids:        make(map[string]*geometry.VoxelGrid),
+		},
+	}, nil
+}
+
+// Get the number of frames. Specifications without an animation have a single
+// frame.
+func (a *Animation) Frames() int {
+	if a.spec.Animation == nil {
+		return 1
+	}
+
+	return a.spec.Animation.Frames
+}
+
+// Create the scene at a frame of the animation.
+func (a *Animation) Scene(frame int) (geometry.Scene, error) {
+	if frame < 0 || frame >= a.Frames() {
+		return geometry.Scene{}, fmt.Errorf("frame %d is out of range (animation has %d frames)", frame, a.Frames())
+	}
+
+	spec := a.spec
+	if spec.Animation != nil {
+		spec = spec.Animation.apply(spec, float64(frame))
+
+		// interpolated values, e.g. of overshooting Bezier curves, may be invalid
+		if err := spec.Validate(); err != nil {
+			return geometry.Scene{}, fmt.Errorf("failed to validate specification at frame %d: %w", frame, err)
+		}
+	}
+
+	return createScene(spec, a.path, a.resources)
+}
+
+func (a AnimationSpec) Validate(spec ImageSpec) error {
+	if err := validate(a.Frames > 0, "animation frames must be greater than 0"); err != nil {
+		return err
+	}
+
+	err := validateMany(
+		validateKeyframes("camera position", a.Camera.Position),
+		validateKeyframes("camera lookAt", a.Camera.LookAt),
+		validateKeyframes("camera up", a.Camera.Up),
+		validateKeyframes("camera fov", a.Camera.Fov),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, light := range a.Lights {
+		err = validateMany(
+			validate(light.Index >= 0 && light.Index < len(spec.Lights), "animated light index %d is out of range", light.Index),
+			validateKeyframes("light direction", light.Direction),
+			validateKeyframes("light color", light.Color),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, sphere := range a.Spheres {
+		err = validateMany(
+			validate(sphere.Index >= 0 && sphere.Index < len(spec.Spheres), "animated sphere index %d is out of range", sphere.Index),
+			validateKeyframes("sphere center", sphere.Center),
+			validateKeyframes("sphere radius", sphere.Radius),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, model := range a.Models {
+		err = validateMany(
+			validate(model.Index >= 0 && model.Index < len(spec.Models), "animated model index %d is out of range", model.Index),
+			validateKeyframes("model center", model.Center),
+			validateKeyframes("model rotation", model.Rotation),
+			validateKeyframes("model size", model.Size),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, prop := range a.SurfaceProps {
+		exists := false
+		for _, specProp := range spec.SurfaceProps {
+			exists = exists || specProp.Name == prop.Name
+		}
+
+		err = validateMany(
+			validate(exists, "animated surface property %q does not exist", prop.Name),
+			validateKeyframes("surface property color", prop.Color),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateKeyframes[T any](name string, keyframes []Keyframe[T]) error {
+	for i, keyframe := range keyframes {
+		validInterpolation := keyframe.Interpolation == "" || keyframe.Interpolation == "linear" || keyframe.Interpolation == "bezier"
+		validEase := keyframe.Ease == nil || (keyframe.Ease[0] >= 0 && keyframe.Ease[0] <= 1 && keyframe.Ease[2] >= 0 && keyframe.Ease[2] <= 1)
+
+		err := validateMany(
+			validate(i == 0 || keyframe.Frame > keyframes[i-1].Frame, "%s keyframes must be sorted by strictly increasing frames", name),
+			validate(validInterpolation, "%s keyframe interpolation must be either \"linear\" or \"bezier\"", name),
+			validate(validEase, "%s keyframe ease x values must be between 0 and 1", name),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get a copy of the specification with the animated values at a frame.
+func (a AnimationSpec) apply(spec ImageSpec, frame float64) ImageSpec {
+	spec.Lights = append([]geometry.Light(nil), spec.Lights...)
+	spec.Spheres = append([]SphereSpec(nil), spec.Spheres...)
+	spec.Models = append([]WavefrontModelSpec(nil), spec.Models...)
+	spec.SurfaceProps = append([]SurfacePropSpec(nil), spec.SurfaceProps...)
+
+	animate(&spec.Camera.Position, a.Camera.Position, frame, lerpVector)
+	animate(&spec.Camera.LookAt, a.Camera.LookAt, frame, lerpVector)
+	animate(&spec.Camera.Up, a.Camera.Up, frame, lerpVector)
+	animate(&spec.Camera.Fov, a.Camera.Fov, frame, lerpFloat)
+
+	for _, light := range a.Lights {
+		animate(&spec.Lights[light.Index].Direction, light.Direction, frame, lerpVector)
+		animate(&spec.Lights[light.Index].Color, light.Color, frame, lerpColor)
+	}
+
+	for _, sphere := range a.Spheres {
+		animate(&spec.Spheres[sphere.Index].Center, sphere.Center, frame, lerpVector)
+		animate(&spec.Spheres[sphere.Index].Radius, sphere.Radius, frame, lerpFloat)
+	}
+
+	for _, model := range a.Models {
+		animate(&spec.Models[model.Index].Center, model.Center, frame, lerpVector)
+		animate(&spec.Models[model.Index].Rotation, model.Rotation, frame, lerpVector)
+		animate(&spec.Models[model.Index].Size, model.Size, frame, lerpFloat)
+	}
+
+	for _, prop := range a.SurfaceProps {
+		for i := range spec.SurfaceProps {
+			if spec.SurfaceProps[i].Name == prop.Name {
+				animate(&spec.SurfaceProps[i].Color, prop.Color, frame, lerpColor)
+			}
+		}
+	}
+
+	return spec
+}
+
+// Set value to the interpolated value of the keyframes at a frame. Without
+// keyframes, value is left unchanged.
+func animate[T any](value *T, keyframes []Keyframe[T], frame float64, lerp func(a, b T, t float64) T) {
+	if len(keyframes) == 0 {
+		return
+	}
+
+	if frame <= keyframes[0].Frame {
+		*value = keyframes[0].Value
+		return
+	}
+
+	for i := 0; i < len(keyframes)-1; i++ {
+		from, to := keyframes[i], keyframes[i+1]
+		if frame >= to.Frame {
+			continue
+		}
+
+		t := (frame - from.Frame) / (to.Frame - from.Frame)
+
+		if from.Interpolation == "bezier" {
+			ease := defaultEase
+			if from.Ease != nil {
+				ease = *from.Ease
+			}
+
+			t = bezierEase(ease, t)
+		}
+
+		*value = lerp(from.Value, to.Value, t)
+		return
+	}
+
+	*value = keyframes[len(keyframes)-1].Value
+}
+
+// Evaluate a cubic Bezier timing curve from (0, 0) to (1, 1) with the control
+// points (x1, y1) and (x2, y2) at time x.
+func bezierEase(ease [4]float64, x float64) float64 {
+	curve := func(p1, p2, s float64) float64 {
+		return 3*(1-s)*(1-s)*s*p1 + 3*(1-s)*s*s*p2 + s*s*s
+	}
+
+	// x(s) is monotonic for control points with x in [0, 1], so bisection
+	// finds the curve parameter of x
+	low, high := 0.0, 1.0
+	for i := 0; i < 50; i++ {
+		middle := (low + high) / 2
+
+		if curve(ease[0], ease[2], middle) < x {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+
+	return curve(ease[1], ease[3], (low+high)/2)
+}
+
+func lerpFloat(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+func lerpVector(a, b geometry.Vector, t float64) geometry.Vector {
+	return geometry.Add(a, geometry.Sprod(geometry.Sub(b, a), t))
+}
+
+func lerpColor(a, b canvas.Color, t float64) canvas.Color {
+	channel := func(a, b uint8) uint8 {
+		return uint8(math.Round(math.Max(0, math.Min(255, lerpFloat(float64(a), float64(b), t)))))
+	}
+
+	return canvas.Color{R: channel(a.R, b.R), G: channel(a.G, b.G), B: channel(a.B, b.B)}
+}
@@ -2,31 +2,95 @@ package specification
 
 import (
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/b-erhart/raytracer/internal/canvas"
 	"github.com/b-erhart/raytracer/internal/geometry"
 )
 
 type ImageSpec struct {
-	Camera       Camera
-	Background   canvas.Color
+	Camera     Camera
+	Background canvas.Color
+	// Vertical gradient over the frame, replacing the background color.
+	BackgroundGradient []geometry.GradientStop
+	// Path of a PNG, JPEG or GIF image stretched to the frame, relative to
+	// the specification file. Replaces the background color and gradient.
+	Backplate    string
+	Environment  *EnvironmentSpec
+	Fog          *FogSpec
+	Sky          *SkySpec
 	Lights       []geometry.Light
 	SurfaceProps []SurfacePropSpec
 	Spheres      []SphereSpec
 	Triangles    []TriangleSpec
 	Models       []WavefrontModelSpec
-	SSAA         bool
+	Volumes      []VolumeSpec
+	Bvh          BvhSpec
+	Render       RenderSpec
+	Progressive  *ProgressiveSpec
+	Antialiasing AntialiasingSpec
+	// Deprecated: use Antialiasing instead. True is the same as 2x2 samples
+	// with a box filter.
+	SSAA      *bool
+	Animation *AnimationSpec
+	// Let shadow rays pass through transparent objects, tinted by their color.
+	TransmissiveShadows bool
+	// Directory for cached wavefront meshes, relative to the specification
+	// file. Defaults to a directory in the user's cache directory.
+	MeshCacheDir     string
+	DisableMeshCache bool
 }
 
 func (i ImageSpec) Validate() error {
 	err := validateMany(
 		i.Camera.Validate(),
-		validate(len(i.Lights) > 0, "at least one light source must be defined"),
+		validate(len(i.Lights) > 0 || i.Environment != nil || i.Sky != nil, "at least one light source, an environment map or a sky must be defined"),
+		validate(i.Environment == nil || i.Sky == nil, "environment map and sky can not be combined"),
+		validateGradient(i.BackgroundGradient),
+		i.Bvh.Validate(),
+		i.Render.Validate(),
+		i.Antialiasing.Validate(),
+		validate(i.SSAA == nil || (i.Antialiasing.Samples == 0 && i.Antialiasing.Filter.Type == "" && i.Antialiasing.Adaptive == nil), "SSAA can not be combined with antialiasing samples, filter or adaptive antialiasing"),
 	)
 	if err != nil {
 		return err
 	}
 
+	if i.Environment != nil {
+		if err = i.Environment.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if i.Fog != nil {
+		if err = i.Fog.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if i.Sky != nil {
+		if err = i.Sky.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if i.Progressive != nil {
+		if err = i.Progressive.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if i.Animation != nil {
+		if err = i.Animation.Validate(i); err != nil {
+			return err
+		}
+	}
+
+	if i.Antialiasing.Adaptive != nil && i.Progressive != nil {
+		return fmt.Errorf("adaptive antialiasing can not be combined with progressive rendering")
+	}
+
 	for _, prop := range i.SurfaceProps {
 		if err = prop.Validate(); err != nil {
 			return err
@@ -51,6 +115,12 @@ func (i ImageSpec) Validate() error {
 		}
 	}
 
+	for _, volume := range i.Volumes {
+		if err = volume.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -63,16 +133,124 @@ type Camera struct {
 	LookAt   geometry.Vector
 	Up       geometry.Vector
 	Fov      float64
+	// One of "perspective" (default), "orthographic", "equirectangular",
+	// "fisheye" or "cubemap".
+	Projection string
+	// Width of the area covered by an orthographic camera.
+	ViewWidth float64
+	Lens      *LensSpec
+	Stereo    *StereoSpec
+	// Brown-Conrady coefficients of the lens.
+	Distortion geometry.Distortion
+	// Strength of the natural vignetting of the lens, from 0 to 1.
+	Vignetting float64
+	// Position at the end of the shutter interval for motion blur.
+	Motion *CameraMotionSpec
+}
+
+// End of a motion during the shutter interval. Omitted values stay unchanged.
+type CameraMotionSpec struct {
+	Position *geometry.Vector
+	LookAt   *geometry.Vector
 }
 
 func (c Camera) Validate() error {
 	return validateMany(
 		validate(c.Resolution.Width > 0, "camera resolution width must be greater than 0"),
 		validate(c.Resolution.Height > 0, "camera resolution height must be greater than 0"),
-		validate(c.Fov > 0 && c.Fov < 180, "camera FOV must be between 0 and 180 degrees"),
+		c.validateProjection(),
 		validate(c.Up != geometry.Vector{}, "camera up vector must not be zero vector"),
 		validate(c.LookAt != geometry.Vector{}, "camera lookAt vector must not be zero vector"),
 		validate(c.Position != c.LookAt, "camera position and lookAt vector must different"),
+		c.validateLens(),
+		c.validateStereo(),
+		validate(!c.panoramic() || c.Distortion == geometry.Distortion{}, "panoramic cameras do not support lens distortion"),
+		validate(!c.panoramic() || c.Vignetting == 0, "panoramic cameras do not support vignetting"),
+		validate(c.Vignetting >= 0 && c.Vignetting <= 1, "camera vignetting must be between 0 and 1"),
+		validate(c.Motion == nil || c.Motion.LookAt == nil || *c.Motion.LookAt != geometry.Vector{}, "camera motion lookAt vector must not be zero vector"),
+	)
+}
+
+func (c Camera) panoramic() bool {
+	return c.Projection == "equirectangular" || c.Projection == "fisheye" || c.Projection == "cubemap"
+}
+
+func (c Camera) validateProjection() error {
+	switch c.Projection {
+	case "", "perspective":
+		return validate(c.Fov > 0 && c.Fov < 180, "camera FOV must be between 0 and 180 degrees")
+	case "orthographic":
+		return validate(c.ViewWidth > 0, "orthographic camera view width must be greater than 0")
+	case "fisheye":
+		return validate(c.Fov > 0 && c.Fov <= 360, "fisheye camera FOV must be between 0 and 360 degrees")
+	case "equirectangular":
+		return nil
+	case "cubemap":
+		return validate(c.Resolution.Width*2 == c.Resolution.Height*3, "cubemap camera resolution must have an aspect ratio of 3:2")
+	default:
+		return fmt.Errorf("camera projection must be one of \"perspective\", \"orthographic\", \"equirectangular\", \"fisheye\" or \"cubemap\"")
+	}
+}
+
+func (c Camera) validateLens() error {
+	if c.Lens == nil {
+		return nil
+	}
+
+	return validateMany(
+		validate(!c.panoramic(), "panoramic cameras do not support a lens"),
+		c.Lens.Validate(),
+	)
+}
+
+func (c Camera) validateStereo() error {
+	if c.Stereo == nil {
+		return nil
+	}
+
+	return validateMany(
+		validate(!c.panoramic(), "panoramic cameras do not support stereo rendering"),
+		c.Stereo.Validate(),
+	)
+}
+
+type StereoSpec struct {
+	InterocularDistance float64
+	// Either "parallel" (default) or "toe-in".
+	Convergence         string
+	ConvergenceDistance float64
+	// One of "side-by-side" (default), "over-under" or "anaglyph".
+	Composite string
+}
+
+func (s StereoSpec) Validate() error {
+	validConvergence := s.Convergence == "" || s.Convergence == "parallel" || s.Convergence == "toe-in"
+	validComposite := s.Composite == "" || s.Composite == "side-by-side" || s.Composite == "over-under" || s.Composite == "anaglyph"
+
+	return validateMany(
+		validate(s.InterocularDistance > 0, "stereo interocular distance must be greater than 0"),
+		validate(validConvergence, "stereo convergence must be either \"parallel\" or \"toe-in\""),
+		validate(s.ConvergenceDistance >= 0, "stereo convergence distance must not be negative"),
+		validate(validComposite, "stereo composite must be one of \"side-by-side\", \"over-under\" or \"anaglyph\""),
+	)
+}
+
+type LensSpec struct {
+	Aperture      float64
+	FocusDistance float64
+	// Point to focus on instead of a fixed distance.
+	FocusPoint *geometry.Vector
+	Blades     int
+	// Rotation of the blades in degrees.
+	BladeRotation float64
+}
+
+func (l LensSpec) Validate() error {
+	return validateMany(
+		validate(l.Aperture >= 0, "lens aperture must not be negative"),
+		validate(l.FocusDistance >= 0, "lens focus distance must not be negative"),
+		validate(l.FocusDistance == 0 || l.FocusPoint == nil, "lens must not have both a focus distance and a focus point"),
+		validate(l.Blades == 0 || l.Blades >= 3, "lens must have either 0 (circular) or at least 3 blades"),
 	)
 }
 
@@ -82,14 +260,74 @@ type SurfacePropSpec struct {
 	Reflectivity float64
 	Mirror       float64
 	Specular     float64
+	Transparency float64
+	// Participating medium filling objects with these properties, which makes
+	// their surfaces invisible.
+	Medium *MediumSpec
+	// Light transport below the surface of translucent materials like wax,
+	// soap or marble.
+	Subsurface *SubsurfaceSpec
 }
 
 func (p SurfacePropSpec) Validate() error {
+	if p.Medium != nil {
+		if err := p.Medium.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if p.Subsurface != nil {
+		if err := p.Subsurface.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return validateMany(
 		validate(p.Name != "", "surface property name must not be empty"),
 		validate(p.Reflectivity >= 0 && p.Reflectivity <= 1, "surface property reflectivity must be between 0 and 1"),
 		validate(p.Mirror >= 0 && p.Mirror <= 1, "surface property mirror must be between 0 and 1"),
 		validate(p.Specular >= 0 && p.Specular <= 1, "surface property specular must be between 0 and 1"),
+		validate(p.Transparency >= 0 && p.Transparency <= 1, "surface property transparency must be between 0 and 1"),
+	)
+}
+
+type MediumSpec struct {
+	// Absorbed and scattered share of light per unit of distance.
+	Absorption float64
+	Scattering float64
+	// Color of the scattered light.
+	Color canvas.Color
+	// Preferred scattering direction, from -1 (backwards) over 0 (evenly in
+	// all directions) to 1 (forwards).
+	Asymmetry float64
+	// Number of points along a ray at which scattered light is gathered.
+	// Defaults to 8.
+	Samples int
+}
+
+func (m MediumSpec) Validate() error {
+	return validateMany(
+		validate(m.Absorption >= 0, "medium absorption must not be negative"),
+		validate(m.Scattering >= 0, "medium scattering must not be negative"),
+		validate(m.Asymmetry > -1 && m.Asymmetry < 1, "medium asymmetry must be greater than -1 and less than 1"),
+		validate(m.Samples >= 0, "medium samples must not be negative"),
+	)
+}
+
+type SubsurfaceSpec struct {
+	// Average distance light travels inside the material between two
+	// scattering events.
+	MeanFreePath float64
+	// Share of light of each channel that survives a scattering event.
+	Albedo canvas.Color
+	// Number of random walks per shading point. Defaults to 16.
+	Walks int
+}
+
+func (s SubsurfaceSpec) Validate() error {
+	return validateMany(
+		validate(s.MeanFreePath > 0, "subsurface mean free path must be greater than 0"),
+		validate(s.Walks >= 0, "subsurface walks must not be negative"),
 	)
 }
 
@@ -97,6 +335,12 @@ type SphereSpec struct {
 	Center      geometry.Vector
 	Radius      float64
 	SurfaceProp string
+	// Center at the end of the shutter interval for motion blur.
+	Motion *SphereMotionSpec
+}
+
+type SphereMotionSpec struct {
+	Center geometry.Vector
 }
 
 func (s SphereSpec) Validate() error {
@@ -126,16 +370,279 @@ type WavefrontModelSpec struct {
 	Center      geometry.Vector
 	Rotation    geometry.Vector
 	SurfaceProp string
+	// Placement at the end of the shutter interval for motion blur.
+	Motion *ModelMotionSpec
+}
+
+// End of a motion during the shutter interval. Omitted values stay unchanged.
+type ModelMotionSpec struct {
+	Center *geometry.Vector
+	// The model turns the shorter way from its rotation to this one, so it
+	// may differ by at most 1 (half a turn) around each axis.
+	Rotation *geometry.Vector
+	Size     float64
 }
 
 func (o WavefrontModelSpec) Validate() error {
+	validRotation := true
+	if o.Motion != nil && o.Motion.Rotation != nil {
+		turn := geometry.Sub(*o.Motion.Rotation, o.Rotation)
+		validRotation = math.Abs(turn.X) <= 1 && math.Abs(turn.Y) <= 1 && math.Abs(turn.Z) <= 1
+	}
+
 	return validateMany(
 		validate(o.Path != "", "model path must not be empty"),
 		validate(o.Size > 0, "model size must be greater than 0"),
+		validate(o.Motion == nil || o.Motion.Size >= 0, "model motion size must not be negative"),
+		validate(validRotation, "model motion rotation must not differ from the rotation by more than 1 (half a turn) around any axis"),
 		validate(o.SurfaceProp != "", "model must have a surface property assigned"),
 	)
 }
 
+// Heterogeneous volume from a voxel grid, stretched over an axis-aligned box.
+type VolumeSpec struct {
+	// Path of the voxel file, relative to the specification file.
+	Path string
+	// Either "text" (default), which starts with the resolution, or "raw" for
+	// little-endian 32 bit floats.
+	Format string
+	// Number of voxels along x, y and z, only for raw files.
+	Resolution [3]int
+	Min        geometry.Vector
+	Max        geometry.Vector
+	// Points mapping densities to emitted color and opacity, sorted by
+	// density.
+	Transfer []geometry.TransferPoint
+	// Distance between samples along a ray. Defaults to half of the smallest
+	// voxel extent.
+	StepSize float64
+}
+
+func (v VolumeSpec) Validate() error {
+	validFormat := v.Format == "" || v.Format == "text" || v.Format == "raw"
+	validResolution := v.Format != "raw" || (v.Resolution[0] > 0 && v.Resolution[1] > 0 && v.Resolution[2] > 0)
+
+	sortedTransfer := true
+	validOpacity := true
+	for i, point := range v.Transfer {
+		sortedTransfer = sortedTransfer && (i == 0 || point.Density > v.Transfer[i-1].Density)
+		validOpacity = validOpacity && point.Opacity >= 0
+	}
+
+	return validateMany(
+		validate(v.Path != "", "volume path must not be empty"),
+		validate(validFormat, "volume format must be either \"text\" or \"raw\""),
+		validate(validResolution, "raw volume resolution must be greater than 0 along all axes"),
+		validate(v.Min.X < v.Max.X && v.Min.Y < v.Max.Y && v.Min.Z < v.Max.Z, "volume min must be less than max along all axes"),
+		validate(len(v.Transfer) > 0, "volume transfer function must have at least one point"),
+		validate(sortedTransfer, "volume transfer points must be sorted by strictly increasing densities"),
+		validate(validOpacity, "volume transfer opacities must not be negative"),
+		validate(v.StepSize >= 0, "volume step size must not be negative"),
+	)
+}
+
+type BvhSpec struct {
+	Split            string
+	MaxLeafSize      int
+	Bins             int
+	TraversalCost    float64
+	IntersectionCost float64
+}
+
+func (b BvhSpec) Validate() error {
+	return validateMany(
+		validate(b.Split == "" || b.Split == "sah" || b.Split == "median", "bvh split must be either \"sah\" or \"median\""),
+		validate(b.MaxLeafSize >= 0, "bvh max. leaf size must not be negative"),
+		validate(b.Bins == 0 || b.Bins >= 2, "bvh bins must be at least 2"),
+		validate(b.TraversalCost >= 0, "bvh traversal cost must not be negative"),
+		validate(b.IntersectionCost >= 0, "bvh intersection cost must not be negative"),
+	)
+}
+
+type RenderSpec struct {
+	Workers   int
+	TileSize  int
+	TileOrder string
+	// Seed of the random numbers for sampling.
+	Seed int64
+}
+
+func (r RenderSpec) Validate() error {
+	validOrder := r.TileOrder == "" || r.TileOrder == "scanline" || r.TileOrder == "spiral" || r.TileOrder == "hilbert"
+
+	return validateMany(
+		validate(r.Workers >= 0, "render workers must not be negative"),
+		validate(r.TileSize >= 0, "render tile size must not be negative"),
+		validate(validOrder, "render tile order must be either \"scanline\", \"spiral\" or \"hilbert\""),
+	)
+}
+
+func validateGradient(stops []geometry.GradientStop) error {
+	for i, stop := range stops {
+		err := validateMany(
+			validate(stop.Position >= 0 && stop.Position <= 1, "background gradient positions must be between 0 and 1"),
+			validate(i == 0 || stop.Position > stops[i-1].Position, "background gradient stops must be sorted by strictly increasing positions"),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Equirectangular environment map, read from a Radiance HDR (.hdr) or portable
+// float map (.pfm) file.
+type EnvironmentSpec struct {
+	// Path of the image, relative to the specification file.
+	Path string
+	// Rotation around the y axis in degrees.
+	Rotation float64
+	// Factor for the radiance of the map. Defaults to 1.
+	Intensity *float64
+	// Number of sampled directions for lighting each shading point. Defaults
+	// to 16, zero only shows the map in the background and in reflections.
+	Samples *int
+}
+
+func (e EnvironmentSpec) Validate() error {
+	return validateMany(
+		validate(e.Path != "", "environment map path must not be empty"),
+		validate(e.Intensity == nil || *e.Intensity >= 0, "environment map intensity must not be negative"),
+		validate(e.Samples == nil || *e.Samples >= 0, "environment map samples must not be negative"),
+	)
+}
+
+// Exponential fog filling the whole scene.
+type FogSpec struct {
+	Color canvas.Color
+	// Extinction per unit of distance at the given height.
+	Density float64
+	// Exponential decrease of the density per unit of height above Height.
+	// Zero gives the same density everywhere.
+	HeightFalloff float64
+	Height        float64
+}
+
+func (f FogSpec) Validate() error {
+	return validateMany(
+		validate(f.Density > 0, "fog density must be greater than 0"),
+		validate(f.HeightFalloff >= 0, "fog height falloff must not be negative"),
+	)
+}
+
+// Analytic daylight sky with a sun light.
+type SkySpec struct {
+	// Angle of the sun above the horizon in degrees.
+	SunElevation float64
+	// Angle of the sun around the y axis in degrees, from the +z towards the
+	// +x direction.
+	SunAzimuth float64
+	// Haziness of the atmosphere, from 2 (clear) to 10 (hazy). Defaults to 3.
+	Turbidity float64
+	// Share of the light at the horizon that the ground reflects. Defaults to
+	// 0.3.
+	GroundAlbedo *float64
+	// Factors for the radiance of the sky and the light of the sun. Default
+	// to 1.
+	Intensity    *float64
+	SunIntensity *float64
+	// Number of sampled directions for lighting each shading point by the
+	// sky. Defaults to 16.
+	Samples *int
+}
+
+func (s SkySpec) Validate() error {
+	return validateMany(
+		validate(s.SunElevation > 0 && s.SunElevation <= 90, "sky sun elevation must be greater than 0 and at most 90"),
+		validate(s.Turbidity == 0 || (s.Turbidity >= 2 && s.Turbidity <= 10), "sky turbidity must be between 2 and 10"),
+		validate(s.GroundAlbedo == nil || (*s.GroundAlbedo >= 0 && *s.GroundAlbedo <= 1), "sky ground albedo must be between 0 and 1"),
+		validate(s.Intensity == nil || *s.Intensity >= 0, "sky intensity must not be negative"),
+		validate(s.SunIntensity == nil || *s.SunIntensity >= 0, "sky sun intensity must not be negative"),
+		validate(s.Samples == nil || *s.Samples >= 0, "sky samples must not be negative"),
+	)
+}
+
+type ProgressiveSpec struct {
+	Samples        int
+	TimeLimit      string
+	NoiseThreshold float64
+}
+
+func (p ProgressiveSpec) Validate() error {
+	timeLimitValid := true
+	if p.TimeLimit != "" {
+		timeLimit, err := time.ParseDuration(p.TimeLimit)
+		timeLimitValid = err == nil && timeLimit > 0
+	}
+
+	return validateMany(
+		validate(p.Samples >= 0, "progressive samples must not be negative"),
+		validate(timeLimitValid, "progressive time limit must be a positive duration like \"30s\""),
+		validate(p.NoiseThreshold >= 0, "progressive noise threshold must not be negative"),
+		validate(p.Samples > 0 || p.TimeLimit != "" || p.NoiseThreshold > 0, "progressive rendering requires a sample, time or noise limit"),
+	)
+}
+
+type AntialiasingSpec struct {
+	// Samples along each axis of a pixel, so a pixel gets Samples*Samples
+	// samples. Adaptive antialiasing sets the samples per pixel itself.
+	Samples  int
+	Sampling string
+	Filter   FilterSpec
+	Adaptive *AdaptiveSpec
+}
+
+func (a AntialiasingSpec) Validate() error {
+	err := validateMany(
+		validate(a.Samples >= 0, "antialiasing samples must not be negative"),
+		validate(a.Sampling == "" || a.Sampling == "stratified" || a.Sampling == "random", "antialiasing sampling must be either \"stratified\" or \"random\""),
+		a.Filter.Validate(),
+		validate(a.Samples == 0 || a.Adaptive == nil, "antialiasing samples can not be combined with adaptive antialiasing, which sets min. and max. samples per pixel"),
+	)
+	if err != nil || a.Adaptive == nil {
+		return err
+	}
+
+	return a.Adaptive.Validate()
+}
+
+type FilterSpec struct {
+	Type   string
+	Radius float64
+	// Falloff of the Gaussian filter.
+	Alpha float64
+	// Parameters of the Mitchell-Netravali filter.
+	B *float64
+	C *float64
+}
+
+func (f FilterSpec) Validate() error {
+	validType := f.Type == "" || f.Type == "box" || f.Type == "tent" || f.Type == "gaussian" || f.Type == "mitchell" || f.Type == "lanczos"
+
+	return validateMany(
+		validate(validType, "filter type must be one of \"box\", \"tent\", \"gaussian\", \"mitchell\" or \"lanczos\""),
+		validate(f.Radius >= 0, "filter radius must not be negative"),
+		validate(f.Alpha >= 0, "filter alpha must not be negative"),
+	)
+}
+
+type AdaptiveSpec struct {
+	// Total samples of every pixel, not samples along each axis.
+	MinSamples int
+	// Total samples of pixels that need refinement.
+	MaxSamples int
+	Threshold  float64
+}
+
+func (a AdaptiveSpec) Validate() error {
+	return validateMany(
+		validate(a.MinSamples > 0, "adaptive min. samples must be greater than 0"),
+		validate(a.MaxSamples >= a.MinSamples, "adaptive max. samples must not be less than min. samples"),
+		validate(a.Threshold > 0, "adaptive threshold must be greater than 0"),
+	)
+}
+
 func validateMany(assertions ...error) error {
 	for _, err := range assertions {
 		if err != nil {
@@ -4,42 +4,93 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/b-erhart/raytracer/internal/canvas"
 	"github.com/b-erhart/raytracer/internal/geometry"
+	"github.com/b-erhart/raytracer/internal/voxel"
 	"github.com/b-erhart/raytracer/internal/wavefront"
 )
 
+// Create the scene of an image specification file. Animated specifications
+// give the scene of the first frame.
 func CreateSceneFromSpecFile(path string) (geometry.Scene, error) {
-	spec, err := readSpecFromFile(path)
+	animation, err := ReadAnimationFromSpecFile(path)
 	if err != nil {
-		return geometry.Scene{}, fmt.Errorf("failed to read image specification: %w", err)
+		return geometry.Scene{}, err
 	}
 
-	objects, err := createObjects(spec, path)
+	return animation.Scene(0)
+}
+
+// Create the scene of a specification. Files are looked up in and added to
+// the resources.
+func createScene(spec ImageSpec, path string, resources *sceneResources) (geometry.Scene, error) {
+	bvhOptions := createBvhOptions(spec.Bvh)
+
+	objects, err := createObjects(spec, path, bvhOptions, resources)
 	if err != nil {
 		return geometry.Scene{}, fmt.Errorf("failed to create objects: %w", err)
 	}
 
-	canvasWidth := spec.Camera.Resolution.Width
-	canvasHeight := spec.Camera.Resolution.Height
-	if spec.SSAA {
-		canvasWidth *= 2
-		canvasHeight *= 2
+	environment, err := createEnvironment(spec.Environment, path, resources.environments)
+	if err != nil {
+		return geometry.Scene{}, fmt.Errorf("failed to create environment: %w", err)
+	}
+
+	background, err := createBackground(spec, path)
+	if err != nil {
+		return geometry.Scene{}, fmt.Errorf("failed to create background: %w", err)
+	}
+
+	lights := spec.Lights
+	if spec.Sky != nil {
+		var sun geometry.Light
+		environment, sun = createSky(*spec.Sky)
+		lights = append(append([]geometry.Light(nil), lights...), sun)
+	}
+
+	canv := canvas.NewCanvas(spec.Camera.Resolution.Width, spec.Camera.Resolution.Height)
+	view := createView(spec.Camera).WithDistortion(spec.Camera.Distortion).WithVignetting(spec.Camera.Vignetting)
+	if spec.Camera.Motion != nil {
+		endEye, endLookAt := spec.Camera.Position, spec.Camera.LookAt
+
+		if spec.Camera.Motion.Position != nil {
+			endEye = *spec.Camera.Motion.Position
+		}
+
+		if spec.Camera.Motion.LookAt != nil {
+			endLookAt = *spec.Camera.Motion.LookAt
+		}
+
+		view = view.WithMotion(endEye, endLookAt)
 	}
+	if spec.Camera.Lens != nil {
+		lens, err := createLens(*spec.Camera.Lens, view)
+		if err != nil {
+			return geometry.Scene{}, fmt.Errorf("failed to create camera lens: %w", err)
+		}
 
-	canv := canvas.NewCanvas(canvasWidth, canvasHeight)
-	view := geometry.NewView(canvasWidth, canvasHeight, spec.Camera.Position, spec.Camera.LookAt, spec.Camera.Up, spec.Camera.Fov)
+		view = view.WithLens(lens)
+	}
 
 	return geometry.Scene{
-		Canvas:     canv,
-		View:       view,
-		Objects:    objects,
-		Lights:     spec.Lights,
-		Background: spec.Background,
-		SSAA:       spec.SSAA,
+		Canvas:              canv,
+		View:                view,
+		Objects:             objects,
+		Lights:              lights,
+		Background:          background,
+		Environment:         environment,
+		Fog:                 createFog(spec.Fog),
+		BvhOptions:          bvhOptions,
+		TransmissiveShadows: spec.TransmissiveShadows,
+		RenderOptions:       createRenderOptions(spec.Render),
+		Progressive:         createProgressiveOptions(spec.Progressive),
+		Antialiasing:        createAntialiasingOptions(spec.Antialiasing, spec.SSAA),
+		Stereo:              createStereoOptions(spec.Camera.Stereo),
 	}, nil
 }
 
@@ -70,7 +121,296 @@ func readSpecFromFile(path string) (ImageSpec, error) {
 	return spec, nil
 }
 
-func createObjects(s ImageSpec, specFilePath string) ([]geometry.Object, error) {
+func createBvhOptions(bvhSpec BvhSpec) geometry.BvhOptions {
+	options := geometry.DefaultBvhOptions()
+
+	if bvhSpec.Split == "median" {
+		options.Split = geometry.SplitMedian
+	}
+
+	if bvhSpec.MaxLeafSize > 0 {
+		options.MaxLeafSize = bvhSpec.MaxLeafSize
+	}
+
+	if bvhSpec.Bins > 0 {
+		options.Bins = bvhSpec.Bins
+	}
+
+	if bvhSpec.TraversalCost > 0 {
+		options.TraversalCost = bvhSpec.TraversalCost
+	}
+
+	if bvhSpec.IntersectionCost > 0 {
+		options.IntersectionCost = bvhSpec.IntersectionCost
+	}
+
+	return options
+}
+
+func createRenderOptions(renderSpec RenderSpec) geometry.RenderOptions {
+	options := geometry.DefaultRenderOptions()
+
+	if renderSpec.Workers > 0 {
+		options.Workers = renderSpec.Workers
+	}
+
+	if renderSpec.TileSize > 0 {
+		options.TileSize = renderSpec.TileSize
+	}
+
+	switch renderSpec.TileOrder {
+	case "spiral":
+		options.TileOrder = geometry.TileOrderSpiral
+	case "hilbert":
+		options.TileOrder = geometry.TileOrderHilbert
+	}
+
+	options.Seed = renderSpec.Seed
+
+	return options
+}
+
+func createProgressiveOptions(progressiveSpec *ProgressiveSpec) geometry.ProgressiveOptions {
+	if progressiveSpec == nil {
+		return geometry.ProgressiveOptions{}
+	}
+
+	// already validated
+	timeLimit, _ := time.ParseDuration(progressiveSpec.TimeLimit)
+
+	return geometry.ProgressiveOptions{
+		MaxSamples:     progressiveSpec.Samples,
+		MaxTime:        timeLimit,
+		NoiseThreshold: progressiveSpec.NoiseThreshold,
+	}
+}
+
+func createView(camera Camera) geometry.View {
+	width := camera.Resolution.Width
+	height := camera.Resolution.Height
+
+	switch camera.Projection {
+	case "orthographic":
+		return geometry.NewOrthographicView(width, height, camera.Position, camera.LookAt, camera.Up, camera.ViewWidth)
+	case "equirectangular":
+		return geometry.NewPanoramicView(width, height, camera.Position, camera.LookAt, camera.Up, geometry.ProjectionEquirectangular, camera.Fov)
+	case "fisheye":
+		return geometry.NewPanoramicView(width, height, camera.Position, camera.LookAt, camera.Up, geometry.ProjectionFisheye, camera.Fov)
+	case "cubemap":
+		return geometry.NewPanoramicView(width, height, camera.Position, camera.LookAt, camera.Up, geometry.ProjectionCubemap, camera.Fov)
+	default:
+		return geometry.NewView(width, height, camera.Position, camera.LookAt, camera.Up, camera.Fov)
+	}
+}
+
+func createLens(lensSpec LensSpec, view geometry.View) (geometry.Lens, error) {
+	lens := geometry.Lens{
+		Aperture:      lensSpec.Aperture,
+		FocusDistance: lensSpec.FocusDistance,
+		Blades:        lensSpec.Blades,
+		BladeRotation: lensSpec.BladeRotation * (math.Pi / 180),
+	}
+
+	if lensSpec.FocusPoint != nil {
+		lens.FocusDistance = view.DistanceTo(*lensSpec.FocusPoint)
+
+		if lens.FocusDistance <= 0 {
+			return geometry.Lens{}, fmt.Errorf("focus point %v is not in front of the camera", *lensSpec.FocusPoint)
+		}
+	}
+
+	return lens, nil
+}
+
+func createBackground(spec ImageSpec, specFilePath string) (geometry.Background, error) {
+	background := geometry.Background{
+		Color:    spec.Background,
+		Gradient: spec.BackgroundGradient,
+	}
+
+	if spec.Backplate == "" {
+		return background, nil
+	}
+
+	absoluteSpecPath, err := filepath.Abs(specFilePath)
+	if err != nil {
+		return geometry.Background{}, fmt.Errorf("failed to get absolute path of specification file: %w", err)
+	}
+
+	background.Image, err = canvas.ReadImage(filepath.Join(filepath.Dir(absoluteSpecPath), spec.Backplate))
+	if err != nil {
+		return geometry.Background{}, fmt.Errorf("failed to read backplate %q: %w", spec.Backplate, err)
+	}
+
+	return background, nil
+}
+
+func createEnvironment(environmentSpec *EnvironmentSpec, specFilePath string, environments map[string]*geometry.EnvironmentMap) (geometry.Environment, error) {
+	if environmentSpec == nil {
+		return geometry.Environment{}, nil
+	}
+
+	absoluteSpecPath, err := filepath.Abs(specFilePath)
+	if err != nil {
+		return geometry.Environment{}, fmt.Errorf("failed to get absolute path of specification file: %w", err)
+	}
+
+	absolutePath := filepath.Join(filepath.Dir(absoluteSpecPath), environmentSpec.Path)
+
+	envMap, exists := environments[absolutePath]
+	if !exists {
+		image, err := canvas.ReadHdrImage(absolutePath)
+		if err != nil {
+			return geometry.Environment{}, fmt.Errorf("failed to read environment map %q: %w", environmentSpec.Path, err)
+		}
+
+		envMap = geometry.NewEnvironmentMap(image)
+		environments[absolutePath] = envMap
+	}
+
+	environment := geometry.Environment{
+		Map:       envMap,
+		Rotation:  environmentSpec.Rotation * (math.Pi / 180),
+		Intensity: 1,
+		Samples:   16,
+	}
+
+	if environmentSpec.Intensity != nil {
+		environment.Intensity = *environmentSpec.Intensity
+	}
+
+	if environmentSpec.Samples != nil {
+		environment.Samples = *environmentSpec.Samples
+	}
+
+	return environment, nil
+}
+
+// Create the environment of a sky and its sun light.
+func createSky(skySpec SkySpec) (geometry.Environment, geometry.Light) {
+	sky := geometry.Sky{
+		SunElevation: skySpec.SunElevation * (math.Pi / 180),
+		SunAzimuth:   skySpec.SunAzimuth * (math.Pi / 180),
+		Turbidity:    orDefault(skySpec.Turbidity, 3),
+		GroundAlbedo: 0.3,
+	}
+
+	if skySpec.GroundAlbedo != nil {
+		sky.GroundAlbedo = *skySpec.GroundAlbedo
+	}
+
+	environment := geometry.Environment{
+		Map:       sky.EnvironmentMap(),
+		Intensity: 1,
+		Samples:   16,
+	}
+
+	if skySpec.Intensity != nil {
+		environment.Intensity = *skySpec.Intensity
+	}
+
+	if skySpec.Samples != nil {
+		environment.Samples = *skySpec.Samples
+	}
+
+	sunIntensity := 1.0
+	if skySpec.SunIntensity != nil {
+		sunIntensity = *skySpec.SunIntensity
+	}
+
+	return environment, sky.Sun(sunIntensity)
+}
+
+func createStereoOptions(stereoSpec *StereoSpec) geometry.StereoOptions {
+	if stereoSpec == nil {
+		return geometry.StereoOptions{}
+	}
+
+	options := geometry.StereoOptions{
+		InterocularDistance: stereoSpec.InterocularDistance,
+		ConvergenceDistance: stereoSpec.ConvergenceDistance,
+	}
+
+	if stereoSpec.Convergence == "toe-in" {
+		options.Convergence = geometry.ConvergenceToeIn
+	}
+
+	switch stereoSpec.Composite {
+	case "over-under":
+		options.Composite = canvas.StereoOverUnder
+	case "anaglyph":
+		options.Composite = canvas.StereoAnaglyph
+	}
+
+	return options
+}
+
+func createAntialiasingOptions(antialiasingSpec AntialiasingSpec, ssaa *bool) geometry.AntialiasingOptions {
+	options := geometry.DefaultAntialiasingOptions()
+
+	if antialiasingSpec.Samples > 0 {
+		options.Samples = antialiasingSpec.Samples
+	} else if ssaa != nil && *ssaa {
+		options.Samples = 2
+	}
+
+	if antialiasingSpec.Sampling == "random" {
+		options.Sampling = geometry.SamplingRandom
+	}
+
+	options.Filter = createPixelFilter(antialiasingSpec.Filter)
+	options.Adaptive = createAdaptiveOptions(antialiasingSpec.Adaptive)
+
+	return options
+}
+
+func createPixelFilter(filterSpec FilterSpec) canvas.PixelFilter {
+	radius := filterSpec.Radius
+
+	switch filterSpec.Type {
+	case "tent":
+		return canvas.NewTentFilter(orDefault(radius, 1))
+	case "gaussian":
+		return canvas.NewGaussianFilter(orDefault(radius, 1.5), orDefault(filterSpec.Alpha, 2))
+	case "mitchell":
+		b, c := 1.0/3, 1.0/3
+		if filterSpec.B != nil {
+			b = *filterSpec.B
+		}
+
+		if filterSpec.C != nil {
+			c = *filterSpec.C
+		}
+
+		return canvas.NewMitchellFilter(orDefault(radius, 2), b, c)
+	case "lanczos":
+		return canvas.NewLanczosFilter(orDefault(radius, 2))
+	default:
+		return canvas.NewBoxFilter(orDefault(radius, 0.5))
+	}
+}
+
+func orDefault(value, defaultValue float64) float64 {
+	if value > 0 {
+		return value
+	}
+
+	return defaultValue
+}
+
+func createAdaptiveOptions(adaptiveSpec *AdaptiveSpec) geometry.AdaptiveOptions {
+	if adaptiveSpec == nil {
+		return geometry.AdaptiveOptions{}
+	}
+
+	return geometry.AdaptiveOptions{
+		MinSamples: adaptiveSpec.MinSamples,
+		MaxSamples: adaptiveSpec.MaxSamples,
+		Threshold:  adaptiveSpec.Threshold,
+	}
+}
+
+func createObjects(s ImageSpec, specFilePath string, bvhOptions geometry.BvhOptions, resources *sceneResources) ([]geometry.Object, error) {
 	props, err := createObjectProps(s.SurfaceProps)
 	if err != nil {
 		return []geometry.Object{}, fmt.Errorf("failed to create object properties: %w", err)
@@ -86,19 +426,70 @@ func createObjects(s ImageSpec, specFilePath string) ([]geometry.Object, error)
 		return []geometry.Object{}, fmt.Errorf("failed to create triangle objects: %w", err)
 	}
 
-	wavefrontModelObjects, err := createWavefrontModelObjects(s.Models, specFilePath, props)
+	wavefrontModelObjects, err := createWavefrontModelObjects(s, specFilePath, props, bvhOptions, resources.meshes)
 	if err != nil {
 		return []geometry.Object{}, fmt.Errorf("failed to create wavefront model objects: %w", err)
 	}
 
-	objs := make([]geometry.Object, 0, len(sphereObjects)+len(triangleObjects)+len(wavefrontModelObjects))
+	volumeObjects, err := createVolumeObjects(s.Volumes, specFilePath, resources.grids)
+	if err != nil {
+		return []geometry.Object{}, fmt.Errorf("failed to create volume objects: %w", err)
+	}
+
+	objs := make([]geometry.Object, 0, len(sphereObjects)+len(triangleObjects)+len(wavefrontModelObjects)+len(volumeObjects))
 	objs = append(objs, sphereObjects...)
 	objs = append(objs, triangleObjects...)
 	objs = append(objs, wavefrontModelObjects...)
+	objs = append(objs, volumeObjects...)
 
 	return objs, nil
 }
 
+func createVolumeObjects(volumeSpecs []VolumeSpec, specFilePath string, grids map[string]*geometry.VoxelGrid) ([]geometry.Object, error) {
+	volumeObjects := make([]geometry.Object, 0, len(volumeSpecs))
+
+	absoluteSpecPath, err := filepath.Abs(specFilePath)
+	if err != nil {
+		return []geometry.Object{}, fmt.Errorf("failed to get absolute path of specification file: %w", err)
+	}
+
+	for _, volume := range volumeSpecs {
+		absolutePath := filepath.Join(filepath.Dir(absoluteSpecPath), volume.Path)
+
+		grid, exists := grids[absolutePath]
+		if !exists {
+			if volume.Format == "raw" {
+				grid, err = voxel.ReadRaw(absolutePath, volume.Resolution[0], volume.Resolution[1], volume.Resolution[2])
+			} else {
+				grid, err = voxel.ReadText(absolutePath)
+			}
+
+			if err != nil {
+				return []geometry.Object{}, fmt.Errorf("failed to read voxel file %q: %w", volume.Path, err)
+			}
+
+			grids[absolutePath] = grid
+		}
+
+		stepSize := volume.StepSize
+		if stepSize == 0 {
+			nx, ny, nz := grid.Resolution()
+			size := geometry.Sub(volume.Max, volume.Min)
+			stepSize = math.Min(size.X/float64(nx), math.Min(size.Y/float64(ny), size.Z/float64(nz))) / 2
+		}
+
+		volumeObjects = append(volumeObjects, &geometry.Volume{
+			Min:      volume.Min,
+			Max:      volume.Max,
+			Grid:     grid,
+			Transfer: volume.Transfer,
+			StepSize: stepSize,
+		})
+	}
+
+	return volumeObjects, nil
+}
+
 func createObjectProps(surfacePropSpecs []SurfacePropSpec) (map[string]geometry.ObjectProps, error) {
 	props := make(map[string]geometry.ObjectProps, len(surfacePropSpecs))
 
@@ -116,12 +507,64 @@ func createObjectProps(surfacePropSpecs []SurfacePropSpec) (map[string]geometry.
 			Reflectivity: prop.Reflectivity,
 			Mirror:       prop.Mirror,
 			Specular:     prop.Specular,
+			Transparency: prop.Transparency,
+			Medium:       createMedium(prop.Medium),
+			Subsurface:   createSubsurface(prop.Subsurface),
 		}
 	}
 
 	return props, nil
 }
 
+func createMedium(mediumSpec *MediumSpec) *geometry.Medium {
+	if mediumSpec == nil {
+		return nil
+	}
+
+	samples := mediumSpec.Samples
+	if samples == 0 {
+		samples = 8
+	}
+
+	return &geometry.Medium{
+		Absorption: mediumSpec.Absorption,
+		Scattering: mediumSpec.Scattering,
+		Color:      mediumSpec.Color,
+		Asymmetry:  mediumSpec.Asymmetry,
+		Samples:    samples,
+	}
+}
+
+func createSubsurface(subsurfaceSpec *SubsurfaceSpec) *geometry.Subsurface {
+	if subsurfaceSpec == nil {
+		return nil
+	}
+
+	walks := subsurfaceSpec.Walks
+	if walks == 0 {
+		walks = 16
+	}
+
+	return &geometry.Subsurface{
+		MeanFreePath: subsurfaceSpec.MeanFreePath,
+		Albedo:       subsurfaceSpec.Albedo,
+		Walks:        walks,
+	}
+}
+
+func createFog(fogSpec *FogSpec) geometry.Fog {
+	if fogSpec == nil {
+		return geometry.Fog{}
+	}
+
+	return geometry.Fog{
+		Color:         fogSpec.Color,
+		Density:       fogSpec.Density,
+		HeightFalloff: fogSpec.HeightFalloff,
+		Height:        fogSpec.Height,
+	}
+}
+
 func createSphereObjects(sphereSpecs []SphereSpec, props map[string]geometry.ObjectProps) ([]geometry.Object, error) {
 	sphereObjects := make([]geometry.Object, 0, len(sphereSpecs))
 
@@ -131,9 +574,15 @@ func createSphereObjects(sphereSpecs []SphereSpec, props map[string]geometry.Obj
 			return []geometry.Object{}, fmt.Errorf("failed to lookup surface properties for sphere: %w", err)
 		}
 
+		var motion geometry.Vector
+		if sphere.Motion != nil {
+			motion = geometry.Sub(sphere.Motion.Center, sphere.Center)
+		}
+
 		sphereObjects = append(sphereObjects, &geometry.Sphere{
 			Center:     sphere.Center,
 			Radius:     sphere.Radius,
+			Motion:     motion,
 			Properties: prop,
 		})
 	}
@@ -161,34 +610,87 @@ func createTriangleObjects(triangleSpecs []TriangleSpec, props map[string]geomet
 	return triangleObjects, nil
 }
 
-func createWavefrontModelObjects(modelSpecs []WavefrontModelSpec, specFilePath string, props map[string]geometry.ObjectProps) ([]geometry.Object, error) {
-	wavefrontObjects := make([]geometry.Object, 0)
+func createWavefrontModelObjects(s ImageSpec, specFilePath string, props map[string]geometry.ObjectProps, bvhOptions geometry.BvhOptions, meshes map[string]*geometry.Mesh) ([]geometry.Object, error) {
+	wavefrontObjects := make([]geometry.Object, 0, len(s.Models))
 
-	for _, objModel := range modelSpecs {
+	absoluteSpecPath, err := filepath.Abs(specFilePath)
+	if err != nil {
+		return []geometry.Object{}, fmt.Errorf("failed to get absolute path of specification file: %w", err)
+	}
+
+	cacheDir := meshCacheDir(s, absoluteSpecPath)
+
+	for _, objModel := range s.Models {
 		prop, err := lookupSurfaceProp(objModel.SurfaceProp, props)
 		if err != nil {
 			return []geometry.Object{}, fmt.Errorf("failed to lookup surface properties for wavefront model: %w", err)
 		}
 
-		absoluteSpecPath, err := filepath.Abs(specFilePath)
-		if err != nil {
-			return []geometry.Object{}, fmt.Errorf("failed to get absolute path of specification file: %w", err)
+		absolutePath := filepath.Join(filepath.Dir(absoluteSpecPath), objModel.Path)
+
+		// models using the same file share one mesh and its bounding volume hierarchy
+		mesh, exists := meshes[absolutePath]
+		if !exists {
+			if cacheDir != "" {
+				mesh, err = wavefront.ReadMeshCached(absolutePath, cacheDir, bvhOptions)
+			} else {
+				mesh, err = wavefront.ReadMesh(absolutePath, bvhOptions)
+			}
+
+			if err != nil {
+				return []geometry.Object{}, fmt.Errorf("failed to read wavefront model: %w", err)
+			}
+
+			meshes[absolutePath] = mesh
 		}
 
-		absolutePath := filepath.Join(filepath.Dir(absoluteSpecPath), objModel.Path)
+		transform := wavefront.Placement(mesh, objModel.Center, objModel.Rotation, objModel.Size)
+
+		if objModel.Motion == nil {
+			wavefrontObjects = append(wavefrontObjects, geometry.NewInstance(mesh, transform, prop))
+			continue
+		}
 
-		wavefrontObjs, err := wavefront.Read(absolutePath, objModel.Center, objModel.Rotation, objModel.Size, prop)
+		endCenter, endRotation, endSize := objModel.Center, objModel.Rotation, objModel.Size
 
-		if err != nil {
-			return []geometry.Object{}, fmt.Errorf("failed to read wavefront model: %w", err)
+		if objModel.Motion.Center != nil {
+			endCenter = *objModel.Motion.Center
 		}
 
-		wavefrontObjects = append(wavefrontObjects, wavefrontObjs...)
+		if objModel.Motion.Rotation != nil {
+			endRotation = *objModel.Motion.Rotation
+		}
+
+		if objModel.Motion.Size > 0 {
+			endSize = objModel.Motion.Size
+		}
+
+		endTransform := wavefront.Placement(mesh, endCenter, endRotation, endSize)
+		wavefrontObjects = append(wavefrontObjects, geometry.NewMovingInstance(mesh, transform, endTransform, prop))
 	}
 
 	return wavefrontObjects, nil
 }
 
+// Get the directory for cached wavefront meshes or an empty string if meshes
+// should not be cached.
+func meshCacheDir(s ImageSpec, absoluteSpecPath string) string {
+	if s.DisableMeshCache {
+		return ""
+	}
+
+	if s.MeshCacheDir != "" {
+		return filepath.Join(filepath.Dir(absoluteSpecPath), s.MeshCacheDir)
+	}
+
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(userCacheDir, "raytracer", "meshes")
+}
+
 func lookupSurfaceProp(name string, props map[string]geometry.ObjectProps) (geometry.ObjectProps, error) {
 	prop, exists := props[name]
 	if !exists {
@@ -0,0 +1,113 @@
+package voxel
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/b-erhart/raytracer/internal/geometry"
+)
+
+// Largest number of voxels of a grid, checked before the grid is allocated.
+const maxVoxels = 1 << 28
+
+// Read a raw voxel file of little-endian 32 bit floats with x varying fastest,
+// then y, then z. The file has no header, so the resolution must be given.
+func ReadRaw(path string, nx, ny, nz int) (*geometry.VoxelGrid, error) {
+	fmt.Printf("reading raw voxel file \"%s\"\n", path)
+
+	if err := checkResolution(nx, ny, nz); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() != int64(4*nx*ny*nz) {
+		return nil, fmt.Errorf("file size %d does not match resolution %dx%dx%d", info.Size(), nx, ny, nz)
+	}
+
+	data := make([]byte, info.Size())
+	if _, err = io.ReadFull(bufio.NewReader(file), data); err != nil {
+		return nil, err
+	}
+
+	densities := make([]float64, nx*ny*nz)
+	for i := range densities {
+		densities[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:])))
+	}
+
+	return geometry.NewVoxelGrid(nx, ny, nz, densities), nil
+}
+
+// Read a text voxel file, which starts with the resolution along x, y and z,
+// followed by the values with x varying fastest, then y, then z. All numbers
+// are separated by whitespace. The file must end after the last value.
+func ReadText(path string) (*geometry.VoxelGrid, error) {
+	fmt.Printf("reading text voxel file \"%s\"\n", path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(file)
+
+	var nx, ny, nz int
+	if _, err = fmt.Fscan(reader, &nx, &ny, &nz); err != nil {
+		return nil, fmt.Errorf("failed to read resolution: %w", err)
+	}
+
+	if err = checkResolution(nx, ny, nz); err != nil {
+		return nil, err
+	}
+
+	// every value takes at least two bytes including its separator
+	if int64(nx*ny*nz) > info.Size()/2 {
+		return nil, fmt.Errorf("file size %d is too small for resolution %dx%dx%d", info.Size(), nx, ny, nz)
+	}
+
+	densities := make([]float64, nx*ny*nz)
+	for i := range densities {
+		if _, err = fmt.Fscan(reader, &densities[i]); err != nil {
+			return nil, fmt.Errorf("failed to read value %d: %w", i, err)
+		}
+	}
+
+	var rest string
+	if _, err = fmt.Fscan(reader, &rest); err != io.EOF {
+		return nil, fmt.Errorf("file contains more than %d values for resolution %dx%dx%d", nx*ny*nz, nx, ny, nz)
+	}
+
+	return geometry.NewVoxelGrid(nx, ny, nz, densities), nil
+}
+
+// Check that all axes have voxels and the grid is not too large to allocate.
+func checkResolution(nx, ny, nz int) error {
+	if nx <= 0 || ny <= 0 || nz <= 0 {
+		return fmt.Errorf("invalid resolution %dx%dx%d", nx, ny, nz)
+	}
+
+	if nx > maxVoxels || ny > maxVoxels || nz > maxVoxels || int64(nx)*int64(ny) > maxVoxels || int64(nx*ny)*int64(nz) > maxVoxels {
+		return fmt.Errorf("resolution %dx%dx%d exceeds %d voxels", nx, ny, nz, maxVoxels)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package wavefront
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/b-erhart/raytracer/internal/geometry"
+)
+
+// Read a wavefront file into a mesh like ReadMesh, but look up the mesh and its
+// bounding volume hierarchy in cacheDir first. Cache files are keyed by a hash
+// of the file content and the hierarchy options, so edited files or changed
+// options never hit a stale entry. Meshes that had to be built are added to
+// the cache. Failing to read or write the cache is not an error.
+func ReadMeshCached(path, cacheDir string, bvhOptions geometry.BvhOptions) (*geometry.Mesh, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cachePath := filepath.Join(cacheDir, cacheKey(content, bvhOptions)+".mesh")
+
+	if mesh, err := readCacheFile(cachePath); err == nil {
+		fmt.Printf("loaded cached mesh for wavefront file \"%s\"\n", path)
+		return mesh, nil
+	} else if !os.IsNotExist(err) {
+		fmt.Printf("ignoring invalid mesh cache file \"%s\": %v\n", cachePath, err)
+	}
+
+	fmt.Printf("reading wavefront file \"%s\"\n", path)
+
+	mesh, err := readMesh(bytes.NewReader(content), bvhOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeCacheFile(cachePath, mesh); err != nil {
+		fmt.Printf("failed to write mesh cache file \"%s\": %v\n", cachePath, err)
+	}
+
+	return mesh, nil
+}
+
+// The mesh is cached in file coordinates, so the placement of the model in the
+// scene is not part of the key and instances with different placements share
+// one entry.
+func cacheKey(content []byte, bvhOptions geometry.BvhOptions) string {
+	hash := sha256.New()
+	hash.Write(content)
+
+	binary.Write(hash, binary.LittleEndian, []float64{
+		geometry.MeshBinaryVersion,
+		float64(bvhOptions.Split),
+		float64(bvhOptions.MaxLeafSize),
+		float64(bvhOptions.Bins),
+		bvhOptions.TraversalCost,
+		bvhOptions.IntersectionCost,
+	})
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func readCacheFile(path string) (*geometry.Mesh, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return geometry.ReadMeshBinary(file)
+}
+
+// Write the cache file to a temporary file first, so concurrent runs never see
+// a partially written cache file.
+func writeCacheFile(path string, mesh *geometry.Mesh) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "mesh-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	err = mesh.WriteBinary(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
@@ -3,6 +3,7 @@ package wavefront
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"slices"
@@ -16,11 +17,23 @@ type fileContent struct {
 	vertices      []geometry.Vector
 	vertexNormals []geometry.Vector
 	faces         []geometry.Triangle
-	maxVertex     geometry.Vector
-	minVertex     geometry.Vector
 }
 
-func Read(path string, origin, rotation geometry.Vector, scaling float64, props geometry.ObjectProps) ([]geometry.Object, error) {
+// Read a wavefront file and place it in the scene. The model is centered at
+// origin, scaled so its largest extent equals scaling and rotated by rotation
+// (in multiples of π around the x, y and z axis).
+func Read(path string, origin, rotation geometry.Vector, scaling float64, props geometry.ObjectProps) (*geometry.Instance, error) {
+	mesh, err := ReadMesh(path, geometry.DefaultBvhOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	return geometry.NewInstance(mesh, Placement(mesh, origin, rotation, scaling), props), nil
+}
+
+// Read a wavefront file into a mesh. The mesh keeps the coordinates of the file
+// and can be placed in the scene by one or more instances.
+func ReadMesh(path string, bvhOptions geometry.BvhOptions) (*geometry.Mesh, error) {
 	fmt.Printf("reading wavefront file \"%s\"\n", path)
 
 	file, err := os.Open(path)
@@ -29,16 +42,49 @@ func Read(path string, origin, rotation geometry.Vector, scaling float64, props
 	}
 	defer file.Close()
 
-	content, err := parseFile(file)
+	return readMesh(file, bvhOptions)
+}
+
+func readMesh(r io.Reader, bvhOptions geometry.BvhOptions) (*geometry.Mesh, error) {
+	content, err := parseFile(r)
 	if err != nil {
 		return nil, err
 	}
 
-	return turnContentToObjects(content, scaling, rotation, origin, props)
+	if len(content.faces) == 0 {
+		return nil, fmt.Errorf("wavefront file does not contain any faces")
+	}
+
+	return geometry.NewMesh(turnContentToObjects(content), bvhOptions), nil
 }
 
-func parseFile(file *os.File) (fileContent, error) {
-	scanner := bufio.NewScanner(file)
+// Get the transformation that centers a mesh at origin, scales it so its
+// largest extent equals scaling and rotates it by rotation (in multiples of π
+// around the x, y and z axis).
+func Placement(mesh *geometry.Mesh, origin, rotation geometry.Vector, scaling float64) geometry.Matrix {
+	minVertex, maxVertex := mesh.Bounds()
+
+	centering := geometry.Vector{
+		X: -minVertex.X - (maxVertex.X-minVertex.X)/2,
+		Y: -minVertex.Y - (maxVertex.Y-minVertex.Y)/2,
+		Z: -minVertex.Z - (maxVertex.Z-minVertex.Z)/2,
+	}
+
+	size := math.Max(maxVertex.X-minVertex.X, math.Max(maxVertex.Y-minVertex.Y, maxVertex.Z-minVertex.Z))
+
+	rotating := geometry.Mul(
+		geometry.RotationZ(rotation.Z*math.Pi),
+		geometry.Mul(geometry.RotationY(rotation.Y*math.Pi), geometry.RotationX(rotation.X*math.Pi)),
+	)
+
+	return geometry.Mul(
+		geometry.Translation(origin),
+		geometry.Mul(rotating, geometry.Mul(geometry.Scaling(scaling/size), geometry.Translation(centering))),
+	)
+}
+
+func parseFile(r io.Reader) (fileContent, error) {
+	scanner := bufio.NewScanner(r)
 	scanner.Split(bufio.ScanLines)
 
 	unsupportedDirectives := make([]string, 0)
@@ -48,8 +94,6 @@ func parseFile(file *os.File) (fileContent, error) {
 		vertices:      make([]geometry.Vector, 0),
 		vertexNormals: make([]geometry.Vector, 0),
 		faces:         make([]geometry.Triangle, 0),
-		maxVertex:     geometry.Vector{X: math.Inf(-1), Y: math.Inf(-1), Z: math.Inf(-1)},
-		minVertex:     geometry.Vector{X: math.Inf(1), Y: math.Inf(1), Z: math.Inf(1)},
 	}
 
 	for scanner.Scan() {
@@ -71,8 +115,6 @@ func parseFile(file *os.File) (fileContent, error) {
 				return fileContent{}, fmt.Errorf("unable to parse vertex on line %d: %v", lineNr, err)
 			}
 
-			updateExtremes(&content.minVertex, &content.maxVertex, newVertex)
-
 			content.vertices = append(content.vertices, newVertex)
 		case "vn":
 			newVertexNormal, err := readVector(words)
@@ -99,52 +141,23 @@ func parseFile(file *os.File) (fileContent, error) {
 	return content, nil
 }
 
-func turnContentToObjects(content fileContent, scaling float64, rotation geometry.Vector, origin geometry.Vector, props geometry.ObjectProps) ([]geometry.Object, error) {
+func turnContentToObjects(content fileContent) []geometry.Object {
 	objs := make([]geometry.Object, 0, len(content.faces))
-	centering := geometry.Vector{
-		X: -content.minVertex.X - (content.maxVertex.X-content.minVertex.X)/2,
-		Y: -content.minVertex.Y - (content.maxVertex.Y-content.minVertex.Y)/2,
-		Z: -content.minVertex.Z - (content.maxVertex.Z-content.minVertex.Z)/2,
-	}
-
 	trianglesPerCorner := make(map[geometry.Vector][]*geometry.Triangle)
-	size := math.Max(content.maxVertex.X-content.minVertex.X, math.Max(content.maxVertex.Y-content.minVertex.Y, content.maxVertex.Z-content.minVertex.Z))
-	scalingFactor := scaling / size
-
-	for _, f := range content.faces {
-		a := geometry.Vector{
-			X: ((f.A.X + centering.X) * scalingFactor),
-			Y: ((f.A.Y + centering.Y) * scalingFactor),
-			Z: ((f.A.Z + centering.Z) * scalingFactor),
-		}
-		b := geometry.Vector{
-			X: ((f.B.X + centering.X) * scalingFactor),
-			Y: ((f.B.Y + centering.Y) * scalingFactor),
-			Z: ((f.B.Z + centering.Z) * scalingFactor),
-		}
-		c := geometry.Vector{
-			X: ((f.C.X + centering.X) * scalingFactor),
-			Y: ((f.C.Y + centering.Y) * scalingFactor),
-			Z: ((f.C.Z + centering.Z) * scalingFactor),
-		}
-
-		a = geometry.Add(rotate(a, rotation), origin)
-		b = geometry.Add(rotate(b, rotation), origin)
-		c = geometry.Add(rotate(c, rotation), origin)
 
-		triangle := &geometry.Triangle{A: a, B: b, C: c, Properties: props}
+	for i := range content.faces {
+		triangle := &content.faces[i]
 
-		if f.NormalsSet {
-			triangle.ASurfaceNormal = rotate(f.ASurfaceNormal, rotation).Normalize()
-			triangle.BSurfaceNormal = rotate(f.BSurfaceNormal, rotation).Normalize()
-			triangle.CSurfaceNormal = rotate(f.CSurfaceNormal, rotation).Normalize()
-			triangle.NormalsSet = true
+		if triangle.NormalsSet {
+			triangle.ASurfaceNormal = triangle.ASurfaceNormal.Normalize()
+			triangle.BSurfaceNormal = triangle.BSurfaceNormal.Normalize()
+			triangle.CSurfaceNormal = triangle.CSurfaceNormal.Normalize()
 		}
 
 		objs = append(objs, triangle)
-		trianglesPerCorner[a] = append(trianglesPerCorner[a], triangle)
-		trianglesPerCorner[b] = append(trianglesPerCorner[b], triangle)
-		trianglesPerCorner[c] = append(trianglesPerCorner[c], triangle)
+		trianglesPerCorner[triangle.A] = append(trianglesPerCorner[triangle.A], triangle)
+		trianglesPerCorner[triangle.B] = append(trianglesPerCorner[triangle.B], triangle)
+		trianglesPerCorner[triangle.C] = append(trianglesPerCorner[triangle.C], triangle)
 	}
 
 	for _, obj := range objs {
@@ -161,7 +174,7 @@ func turnContentToObjects(content fileContent, scaling float64, rotation geometr
 		}
 	}
 
-	return objs, nil
+	return objs
 }
 
 func calculateCornerNormal(corner geometry.Vector, triangle *geometry.Triangle, trianglesPerCorner map[geometry.Vector][]*geometry.Triangle) geometry.Vector {
@@ -282,38 +295,3 @@ func readFace(words []string, vertices, vertexNormals []geometry.Vector) ([]geom
 
 	return triangles, nil
 }
-
-func rotate(vertex, rotation geometry.Vector) geometry.Vector {
-	// rotate around x axis
-	xRotated := geometry.Vector{
-		X: vertex.X,
-		Y: vertex.Y*math.Cos(rotation.X*math.Pi) - vertex.Z*math.Sin(rotation.X*math.Pi),
-		Z: vertex.Y*math.Sin(rotation.X*math.Pi) + vertex.Z*math.Cos(rotation.X*math.Pi),
-	}
-
-	// rotate around y axis
-	xyRotated := geometry.Vector{
-		X: xRotated.X*math.Cos(rotation.Y*math.Pi) + xRotated.Z*math.Sin(rotation.Y*math.Pi),
-		Y: xRotated.Y,
-		Z: -xRotated.X*math.Sin(rotation.Y*math.Pi) + xRotated.Z*math.Cos(rotation.Y*math.Pi),
-	}
-
-	// rotate around z axis
-	xyzRotated := geometry.Vector{
-		X: xyRotated.X*math.Cos(rotation.Z*math.Pi) - xyRotated.Y*math.Sin(rotation.Z*math.Pi),
-		Y: xyRotated.X*math.Sin(rotation.Z*math.Pi) + xyRotated.Y*math.Cos(rotation.Z*math.Pi),
-		Z: xyRotated.Z,
-	}
-
-	return xyzRotated
-}
-
-func updateExtremes(minVertex, maxVertex *geometry.Vector, newVertex geometry.Vector) {
-	minVertex.X = math.Min(minVertex.X, newVertex.X)
-	minVertex.Y = math.Min(minVertex.Y, newVertex.Y)
-	minVertex.Z = math.Min(minVertex.Z, newVertex.Z)
-
-	maxVertex.X = math.Max(maxVertex.X, newVertex.X)
-	maxVertex.Y = math.Max(maxVertex.Y, newVertex.Y)
-	maxVertex.Z = math.Max(maxVertex.Z, newVertex.Z)
-}
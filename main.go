@@ -1,16 +1,45 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"math"
 	"os"
+	"os/signal"
 	"runtime/pprof"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/b-erhart/raytracer/internal/canvas"
 	"github.com/b-erhart/raytracer/internal/geometry"
 	"github.com/b-erhart/raytracer/internal/specification"
 )
 
 func main() {
+	timeout := flag.Duration("timeout", 0, "abort rendering after this duration (0 for no limit)")
+	frameRange := flag.String("frames", "", "render a range of animation frames like \"0-47\"")
+	turntable := flag.Int("turntable", 0, "render this many frames orbiting the camera once around the scene center")
+	format := flag.String("format", "ppm", "output format: \"ppm\" (one file per frame), \"gif\" or \"apng\"")
+	fps := flag.Int("fps", 24, "frame rate of GIF and APNG animations")
+	flag.Parse()
+
+	if *format != "ppm" && *format != "gif" && *format != "apng" {
+		fmt.Fprintf(os.Stderr, "unknown output format %q", *format)
+		os.Exit(1)
+	}
+
+	if *fps <= 0 {
+		fmt.Fprintf(os.Stderr, "frame rate must be greater than 0")
+		os.Exit(1)
+	}
+
+	if *turntable < 0 || (*turntable > 0 && *frameRange != "") {
+		fmt.Fprintf(os.Stderr, "turntable frames must be positive and cannot be combined with an animation frame range")
+		os.Exit(1)
+	}
+
 	f, err := os.Create("raytracer.prof")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to create profiling file: %v", err)
@@ -20,7 +49,7 @@ func main() {
 	pprof.StartCPUProfile(f)
 	defer pprof.StopCPUProfile()
 
-	scene, err := specification.CreateSceneFromSpecFile("SPEC/image.json")
+	animation, err := specification.ReadAnimationFromSpecFile("SPEC/image.json")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to read image specification: %v", err)
 		os.Exit(1)
@@ -28,27 +57,247 @@ func main() {
 
 	fmt.Println("Image spec read successfully!")
 
-	if scene.SSAA {
-		fmt.Println("SSAA enabled - rendering at doubled resolution...")
+	first, last := 0, 0
+	if *frameRange != "" {
+		first, last, err = parseFrameRange(*frameRange, animation.Frames())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid frame range: %v", err)
+			os.Exit(1)
+		}
+	} else if *turntable > 0 {
+		last = *turntable - 1
 	}
 
-	raytracer := geometry.NewRaytracer(scene.Objects, scene.Lights, scene.Background)
+	sequence := *frameRange != "" || *turntable > 0
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	var frames []*canvas.Canvas
+
+	for frame := first; frame <= last; frame++ {
+		path := "./output.ppm"
+		if sequence {
+			path = fmt.Sprintf("./frame_%04d.ppm", frame)
+			fmt.Printf("Frame %d (%d-%d):\n", frame, first, last)
+		}
+
+		var scene geometry.Scene
+		if *turntable > 0 {
+			scene, err = animation.Scene(0)
+			scene.View = scene.View.Orbit(scene.Center(), 2*math.Pi*float64(frame)/float64(*turntable))
+		} else {
+			scene, err = animation.Scene(frame)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create scene: %v", err)
+			os.Exit(1)
+		}
+
+		// animations are only written once all frames are done
+		snapshot := func(*canvas.Canvas) {}
+		if *format == "ppm" {
+			snapshot = func(canv *canvas.Canvas) {
+				if err := canv.WriteToPpm(path); err != nil {
+					fmt.Fprintf(os.Stderr, "failed to write snapshot: %v\n", err)
+				}
+			}
+		}
+
+		output, err := renderImage(ctx, scene, snapshot, frame == first)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v", err)
+			os.Exit(1)
+		}
+
+		if *format != "ppm" {
+			frames = append(frames, output)
+			continue
+		}
+
+		fmt.Println("Writing PPM file...")
+		if err = output.WriteToPpm(path); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write PPM file: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	delay := time.Second / time.Duration(*fps)
+
+	switch *format {
+	case "gif":
+		fmt.Println("Writing GIF file...")
+		err = canvas.WriteGif("./output.gif", frames, delay)
+	case "apng":
+		fmt.Println("Writing APNG file...")
+		err = canvas.WriteApng("./output.png", frames, delay)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write animation: %v", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Done!")
+}
+
+// Parse a frame range like "3-10" or a single frame like "7".
+func parseFrameRange(frameRange string, frames int) (int, int, error) {
+	start, end, isRange := strings.Cut(frameRange, "-")
+	if !isRange {
+		end = start
+	}
+
+	first, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse first frame: %w", err)
+	}
+
+	last, err := strconv.Atoi(end)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse last frame: %w", err)
+	}
+
+	if first < 0 || last < first || last >= frames {
+		return 0, 0, fmt.Errorf("frames %d to %d are not within the %d frames of the animation", first, last, frames)
+	}
+
+	return first, last, nil
+}
+
+// Render the scene and return the image. Progressive rendering passes
+// intermediate images to snapshot. The scene builds on meshes whose
+// hierarchies already exist, so only the top level is built here.
+func renderImage(ctx context.Context, scene geometry.Scene, snapshot func(*canvas.Canvas), printStats bool) (*canvas.Canvas, error) {
+	raytracer := geometry.NewRaytracer(scene)
+	if printStats {
+		printBvhStats(scene, raytracer)
+	}
 
 	fmt.Println("Rendering image...")
 	start := time.Now()
-	raytracer.Render(scene.View, scene.Canvas)
+
+	output := scene.Canvas
+	var err error
+	if scene.Stereo.Enabled() {
+		output, err = renderStereo(ctx, raytracer, scene, snapshot)
+	} else {
+		err = render(ctx, raytracer, scene, scene.View, scene.Canvas, snapshot)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rendering aborted: %w", err)
+	}
 	elapsed := time.Since(start)
 	fmt.Printf("Rendering done! (took %s)\n", elapsed)
-	if scene.SSAA {
-		scene.Canvas = scene.Canvas.CreateSSAACanvas()
+
+	return output, nil
+}
+
+// Render the view onto the canvas in the mode configured by the scene.
+// Progressive rendering passes intermediate images to snapshot.
+func render(ctx context.Context, raytracer *geometry.Raytracer, scene geometry.Scene, view geometry.View, canv *canvas.Canvas, snapshot func(*canvas.Canvas)) error {
+	if scene.Progressive.Enabled() {
+		return renderProgressive(ctx, raytracer, scene, view, canv, snapshot)
+	} else if scene.Antialiasing.Adaptive.Enabled() {
+		return renderAdaptive(ctx, raytracer, scene, view, canv)
 	}
 
-	fmt.Println("Writing PPM file...")
-	err = scene.Canvas.WriteToPpm("./output.ppm")
+	err := raytracer.RenderContext(ctx, view, canv, printProgress)
+	fmt.Println()
 
+	return err
+}
+
+// Render the views of both eyes one after another and combine them into a
+// new canvas.
+func renderStereo(ctx context.Context, raytracer *geometry.Raytracer, scene geometry.Scene, snapshot func(*canvas.Canvas)) (*canvas.Canvas, error) {
+	leftView, rightView := scene.View.StereoPair(scene.Stereo)
+	left := scene.Canvas
+	right := canvas.NewCanvas(left.Width(), left.Height())
+
+	fmt.Println("Left eye:")
+	err := render(ctx, raytracer, scene, leftView, left, func(canv *canvas.Canvas) {
+		snapshot(canvas.Composite(canv, right, scene.Stereo.Composite))
+	})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to write PPM file: %v", err)
-		os.Exit(1)
+		return nil, err
 	}
-	fmt.Println("Done!")
+
+	fmt.Println("Right eye:")
+	err = render(ctx, raytracer, scene, rightView, right, func(canv *canvas.Canvas) {
+		snapshot(canvas.Composite(left, canv, scene.Stereo.Composite))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return canvas.Composite(left, right, scene.Stereo.Composite), nil
+}
+
+// Render progressively and pass a snapshot of the image to snapshot at most
+// once per second. The final estimate ends up in the canvas.
+func renderProgressive(ctx context.Context, raytracer *geometry.Raytracer, scene geometry.Scene, view geometry.View, canv *canvas.Canvas, snapshot func(*canvas.Canvas)) error {
+	acc := canvas.NewAccumulator(canv.Width(), canv.Height())
+	lastSnapshot := time.Now()
+
+	err := raytracer.RenderProgressive(ctx, view, acc, scene.Progressive, func(status geometry.PassStatus) {
+		fmt.Printf(
+			"Pass %d done - %d samples per pixel, noise %.4f (%s)\n",
+			status.Pass, status.SamplesPerPixel, status.Noise, status.Elapsed.Round(time.Millisecond),
+		)
+
+		if time.Since(lastSnapshot) >= time.Second {
+			snapshot(acc.Canvas())
+			lastSnapshot = time.Now()
+		}
+	})
+
+	*canv = *acc.Canvas()
+
+	return err
+}
+
+// Render with adaptive antialiasing. The result ends up in the canvas.
+func renderAdaptive(ctx context.Context, raytracer *geometry.Raytracer, scene geometry.Scene, view geometry.View, canv *canvas.Canvas) error {
+	acc := canvas.NewAccumulator(canv.Width(), canv.Height())
+
+	err := raytracer.RenderAdaptive(ctx, view, acc, scene.Antialiasing.Adaptive, printProgress)
+	fmt.Println()
+
+	*canv = *acc.Canvas()
+
+	return err
+}
+
+func printBvhStats(scene geometry.Scene, raytracer *geometry.Raytracer) {
+	fmt.Printf("Top-level BVH: %v\n", raytracer.BvhStats())
+
+	printed := make(map[*geometry.Mesh]bool)
+
+	for _, obj := range scene.Objects {
+		instance, isInstance := obj.(*geometry.Instance)
+		if !isInstance || printed[instance.Mesh()] {
+			continue
+		}
+
+		fmt.Printf("Mesh BVH: %v\n", instance.Mesh().BvhStats())
+		printed[instance.Mesh()] = true
+	}
+}
+
+func printProgress(progress geometry.Progress) {
+	const barWidth = 30
+
+	filled := int(progress.Percent / 100 * barWidth)
+	bar := strings.Repeat("#", filled) + strings.Repeat(" ", barWidth-filled)
+
+	fmt.Printf(
+		"\r[%s] %5.1f%% | %6.2f Mrays/s | ETA %-8s",
+		bar, progress.Percent, progress.RaysPerSecond/1e6, progress.ETA.Round(time.Second),
+	)
 }